@@ -0,0 +1,157 @@
+package lradix
+
+import "fmt"
+
+// Aggregator describes a summary of type A kept for every subtree, such as the number of keys,
+// the sum of weights or the latest timestamp below a node. Combine must be associative and
+// commutative with Identity as its neutral element, because children are combined in storage order.
+type Aggregator[T, A any] struct {
+	Identity  A              // summary of an empty subtree
+	Combine   func(a, b A) A // merges the summaries of two disjoint sets of keys
+	FromValue func(val *T) A // summary of a single stored key
+}
+
+// WithAggregator makes every node cache the combined summary of its subtree, which is maintained
+// on Insert and RemoveNode and read with SubtreeSummary or ConcurrentSubtreeSummary. Each write
+// recomputes the summaries along its path up to the root, combining the children of every node
+// on the way. Combine and FromValue run under the tree's locks in ConcurrentTree and must not
+// call back into the tree, which would deadlock. T must match the value type of the tree,
+// otherwise NewTree and NewConcurrentTree panic.
+func WithAggregator[T, A any](agg Aggregator[T, A]) Option {
+	return func(o *treeOptions) {
+		o.aggregator = agg
+	}
+}
+
+// aggregator is the type-erased form of an Aggregator used by the trees, whose summaries are stored as any.
+type aggregator[T any] interface {
+	own(end bool, val *T) any
+	combine(a, b any) any
+}
+
+// own returns the summary of a node's own key, or the identity for intermediate nodes.
+func (agg Aggregator[T, A]) own(end bool, val *T) any {
+	if !end {
+		return agg.Identity
+	}
+	return agg.FromValue(val)
+}
+
+// combine merges a summary with a child's summary. A child created concurrently may not have
+// been summarized yet, in which case it is skipped until its own update reaches the parent.
+func (agg Aggregator[T, A]) combine(a, b any) any {
+	if b == nil {
+		return a
+	}
+	return agg.Combine(a.(A), b.(A))
+}
+
+func newAggregator[T any](o treeOptions) aggregator[T] {
+	if o.aggregator == nil {
+		return nil
+	}
+	agg, ok := o.aggregator.(aggregator[T])
+	if !ok {
+		panic(fmt.Sprintf("lradix: aggregator %T does not match value type %T", o.aggregator, *new(T)))
+	}
+	return agg
+}
+
+// summarizeSubtree recomputes the cached summaries of node and of every node below it,
+// children first, for subtrees built without going through Insert, such as decoded trees.
+func (t *Tree[K, T]) summarizeSubtree(node *Node[K, T]) {
+	if t.agg == nil {
+		return
+	}
+	summary := t.agg.own(node.End, node.Val)
+	node.children.each(func(_ K, child *Node[K, T]) bool {
+		t.summarizeSubtree(child)
+		summary = t.agg.combine(summary, child.summary)
+		return true
+	})
+	node.summary = summary
+}
+
+// summarizeSubtree is Tree.summarizeSubtree for a ConcurrentTree. It takes no locks,
+// so the subtree must not be reachable by other goroutines yet.
+func (t *ConcurrentTree[K, T]) summarizeSubtree(node *ConcurrentNode[K, T]) {
+	if t.agg == nil {
+		return
+	}
+	summary := t.agg.own(node.End, node.Val)
+	node.children.each(func(_ K, child *ConcurrentNode[K, T]) bool {
+		t.summarizeSubtree(child)
+		summary = t.agg.combine(summary, child.summary)
+		return true
+	})
+	node.summary = summary
+}
+
+// SubtreeSummary returns the combined summary of every stored key that starts with prefix,
+// as cached by the tree's Aggregator. The prefix may end inside a node's text. The boolean is
+// false if no stored key starts with prefix. It panics if the tree was not created WithAggregator
+// for summaries of type A.
+func SubtreeSummary[A any, K comparable, T any](t *Tree[K, T], prefix []K) (A, bool) {
+	if t.agg == nil {
+		panic("lradix: SubtreeSummary on a tree created without an aggregator")
+	}
+	mark := t.Root
+	index := 0
+	for index < len(prefix) {
+		next, ok := mark.GetChild(prefix[index])
+		if !ok {
+			return *new(A), false
+		}
+		sharedPrefix := longestPrefix(next.Text, prefix[index:])
+		if sharedPrefix < len(next.Text) && index+sharedPrefix < len(prefix) {
+			return *new(A), false
+		}
+		index += sharedPrefix
+		mark = next
+	}
+	return summaryOf[A](mark.summary, mark == t.Root && t.size == 0)
+}
+
+// ConcurrentSubtreeSummary is SubtreeSummary for a ConcurrentTree. Nodes are read-locked one at
+// a time, so under concurrent writes the summary may not yet include writes still propagating
+// towards the root.
+func ConcurrentSubtreeSummary[A any, K comparable, T any](t *ConcurrentTree[K, T], prefix []K) (A, bool) {
+	if t.agg == nil {
+		panic("lradix: ConcurrentSubtreeSummary on a tree created without an aggregator")
+	}
+	mark := t.Root
+	index := 0
+	for index < len(prefix) {
+		mark.RLock()
+		next, ok := mark.GetChild(prefix[index])
+		mark.RUnlock()
+		if !ok {
+			return *new(A), false
+		}
+		next.RLock()
+		matchText := next.Text
+		next.RUnlock()
+		sharedPrefix := longestPrefix(matchText, prefix[index:])
+		if sharedPrefix < len(matchText) && index+sharedPrefix < len(prefix) {
+			return *new(A), false
+		}
+		index += sharedPrefix
+		mark = next
+	}
+	mark.RLock()
+	summary := mark.summary
+	mark.RUnlock()
+	return summaryOf[A](summary, mark == t.Root && t.Len() == 0)
+}
+
+// summaryOf converts a cached summary to A. empty is set for the root of an empty tree.
+func summaryOf[A any](summary any, empty bool) (A, bool) {
+	if summary == nil || empty {
+		return *new(A), false
+	}
+	s, ok := summary.(A)
+	if !ok {
+		panic(fmt.Sprintf("lradix: subtree summary is %T, not %T", summary, *new(A)))
+	}
+	return s, true
+}
@@ -0,0 +1,199 @@
+package lradix
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// weights sums the stored values and counts the keys of a subtree.
+type weights struct {
+	keys int
+	sum  int
+}
+
+var weightAggregator = Aggregator[int, weights]{
+	Identity: weights{},
+	Combine: func(a, b weights) weights {
+		return weights{a.keys + b.keys, a.sum + b.sum}
+	},
+	FromValue: func(val *int) weights {
+		return weights{1, *val}
+	},
+}
+
+func TestSubtreeSummary(t *testing.T) {
+	tree := NewTree[byte, int](WithAggregator(weightAggregator))
+	if _, ok := SubtreeSummary[weights](tree, nil); ok {
+		t.Error("SubtreeSummary reported keys in an empty tree")
+	}
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2)
+	tree.Insert([]byte("helium"), 4)
+	hel := tree.Insert([]byte("hel"), 8)
+	tree.Insert([]byte("world"), 16)
+
+	testCases := []struct {
+		prefix   string
+		expected weights
+		ok       bool
+	}{
+		{"", weights{5, 31}, true},
+		{"h", weights{4, 15}, true},
+		{"hel", weights{4, 15}, true},
+		{"hell", weights{1, 1}, true},
+		{"heli", weights{1, 4}, true},
+		{"w", weights{1, 16}, true},
+		{"hex", weights{}, false},
+		{"hello world", weights{}, false},
+	}
+	for _, tc := range testCases {
+		if got, ok := SubtreeSummary[weights](tree, []byte(tc.prefix)); got != tc.expected || ok != tc.ok {
+			t.Errorf("SubtreeSummary(%q) = %v, %v, expected %v, %v", tc.prefix, got, ok, tc.expected, tc.ok)
+		}
+	}
+
+	tree.Insert([]byte("help"), 32) // overwrite
+	tree.RemoveNode(hel)
+	tree.Delete([]byte("world"))
+	for _, tc := range []struct {
+		prefix   string
+		expected weights
+	}{
+		{"", weights{3, 37}},
+		{"hel", weights{3, 37}},
+		{"help", weights{1, 32}},
+	} {
+		if got, _ := SubtreeSummary[weights](tree, []byte(tc.prefix)); got != tc.expected {
+			t.Errorf("SubtreeSummary(%q) = %v after removals, expected %v", tc.prefix, got, tc.expected)
+		}
+	}
+	if got, _ := SubtreeSummary[weights](tree.Clone(), nil); got != (weights{3, 37}) {
+		t.Errorf("SubtreeSummary of a clone = %v, expected the original's summary", got)
+	}
+}
+
+func TestSubtreeSummaryRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tree := NewTree[byte, int](WithAggregator(weightAggregator))
+	for i := 0; i < 2000; i++ {
+		key := []byte(randomKey(r))
+		if r.Intn(3) == 0 {
+			tree.Delete(key)
+		} else {
+			tree.Insert(key, i)
+		}
+		prefix := key[:r.Intn(len(key)+1)]
+		got, _ := SubtreeSummary[weights](tree, prefix)
+		if expected := walkWeights(tree.WalkPrefix, prefix); got != expected {
+			t.Fatalf("step %d: SubtreeSummary(%q) = %v, expected %v\n%s", i, prefix, got, expected, tree.String())
+		}
+	}
+}
+
+// walkWeights computes the weights of the keys starting with prefix by walking them.
+func walkWeights(walk func(prefix []byte, fn func(key []byte, val *int) bool), prefix []byte) weights {
+	var w weights
+	walk(prefix, func(_ []byte, val *int) bool {
+		w = weightAggregator.Combine(w, weightAggregator.FromValue(val))
+		return true
+	})
+	return w
+}
+
+func TestConcurrentSubtreeSummary(t *testing.T) {
+	tree := NewConcurrentTree[byte, int](WithAggregator(weightAggregator))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < 500; i++ {
+				key := []byte(randomKey(r))
+				if r.Intn(3) == 0 {
+					tree.Delete(key)
+				} else {
+					tree.Insert(key, i)
+				}
+				ConcurrentSubtreeSummary[weights](tree, key[:1])
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+
+	for _, prefix := range []string{"", "a", "b", "ab", "abc", "cc"} {
+		got, _ := ConcurrentSubtreeSummary[weights](tree, []byte(prefix))
+		if expected := walkWeights(tree.WalkPrefix, []byte(prefix)); got != expected {
+			t.Errorf("ConcurrentSubtreeSummary(%q) = %v, expected %v", prefix, got, expected)
+		}
+	}
+	if got, _ := SubtreeSummary[weights](tree.Snapshot(), nil); got.keys != tree.Len() {
+		t.Errorf("SubtreeSummary of a snapshot counts %d keys, expected %d", got.keys, tree.Len())
+	}
+}
+
+func TestSubtreeSummaryUnmarshalBinary(t *testing.T) {
+	src := NewTree[byte, int]()
+	src.Insert([]byte("hello"), 1)
+	src.Insert([]byte("help"), 2)
+	src.Insert([]byte("world"), 4)
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+
+	dst := NewTree[byte, int](WithAggregator(weightAggregator))
+	if err := dst.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	cdst := NewConcurrentTree[byte, int](WithAggregator(weightAggregator))
+	if err := cdst.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	testCases := []struct {
+		prefix   string
+		expected weights
+	}{
+		{"", weights{3, 7}},
+		{"hel", weights{2, 3}},
+		{"help", weights{1, 2}},
+		{"w", weights{1, 4}},
+	}
+	for _, tc := range testCases {
+		if got, ok := SubtreeSummary[weights](dst, []byte(tc.prefix)); !ok || got != tc.expected {
+			t.Errorf("SubtreeSummary(%q) = %v, %v after UnmarshalBinary, expected %v, true", tc.prefix, got, ok, tc.expected)
+		}
+		if got, ok := ConcurrentSubtreeSummary[weights](cdst, []byte(tc.prefix)); !ok || got != tc.expected {
+			t.Errorf("ConcurrentSubtreeSummary(%q) = %v, %v after UnmarshalBinary, expected %v, true", tc.prefix, got, ok, tc.expected)
+		}
+	}
+	// later writes keep the recomputed summaries up to date
+	dst.Insert([]byte("helium"), 8)
+	if got, _ := SubtreeSummary[weights](dst, []byte("hel")); got != (weights{3, 11}) {
+		t.Errorf("SubtreeSummary(hel) = %v after Insert, expected {3 11}", got)
+	}
+}
+
+func TestSubtreeSummaryMisuse(t *testing.T) {
+	expectPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected %s to panic", name)
+			}
+		}()
+		fn()
+	}
+	expectPanic("SubtreeSummary without an aggregator", func() {
+		SubtreeSummary[int](NewTree[byte, int](), nil)
+	})
+	expectPanic("NewTree with an aggregator for another value type", func() {
+		NewTree[byte, string](WithAggregator(weightAggregator))
+	})
+	expectPanic("SubtreeSummary with another summary type", func() {
+		tree := NewTree[byte, int](WithAggregator(weightAggregator))
+		tree.Insert([]byte("a"), 1)
+		SubtreeSummary[int](tree, nil)
+	})
+}
@@ -0,0 +1,495 @@
+package lradix
+
+import (
+	"math"
+	"slices"
+	"strings"
+)
+
+// arenaNil marks a missing node index in an ArenaTree.
+const arenaNil int32 = -1
+
+// ArenaTree is a radix tree that stores its nodes in a flat slice and links them with
+// int32 indexes instead of pointers, and keeps every text fragment in one shared backing array.
+// Neither the node slice nor the child index hold pointers, so the garbage collector does not
+// need to scan them, which keeps GC mark phases short for trees with tens of millions of nodes.
+//
+// Insert, LongestCommonPrefixMatch and RemoveNode behave like those of Tree. Intermediate nodes
+// carry the most recently inserted value below them, as in a Tree created with the default
+// ValuePolicy, while nodes storing a key keep their own. Nodes are identified by their index, returned by
+// Insert; slots of removed nodes are reused through a free list, so an index must not be used
+// after its node has been removed. Values are stored by value rather than through pointers.
+//
+// ArenaTree is not safe for concurrent use. Text fragments are limited to math.MaxInt32
+// elements in total.
+type ArenaTree[K comparable, T any] struct {
+	nodes   []arenaNode            // node slots, the root is at index 0
+	vals    []T                    // value of each node slot
+	text    []K                    // shared backing array of all text fragments
+	edges   map[arenaEdge[K]]int32 // child lookup by parent and first text element
+	free    int32                  // first free node slot, linked through next
+	garbage int                    // elements of text no longer referenced by any node
+	size    int                    // number of stored keys, maintained by Insert and RemoveNode
+	seq     uint64                 // sequence number of the last Insert
+}
+
+// arenaEdge identifies the child of parent whose text starts with head.
+type arenaEdge[K comparable] struct {
+	parent int32
+	head   K
+}
+
+// arenaNode is a node of an ArenaTree. Children form a singly linked list through next,
+// starting at child, used for iteration; lookups go through the tree's edge index.
+// Free slots are linked through next as well.
+type arenaNode struct {
+	textOff int32 // start of the node's text in the shared text array
+	textLen int32
+	parent  int32
+	child   int32  // first child
+	next    int32  // next sibling, or next free slot
+	seq     uint64 // insertion sequence number of the node's value, used to derive intermediate values
+	hasVal  bool
+	end     bool // whether this node represents the end of a complete key
+	live    bool // false for free slots
+}
+
+// NewArenaTree creates a new empty arena radix tree with keys of type K and values of type T.
+func NewArenaTree[K comparable, T any]() *ArenaTree[K, T] {
+	t := &ArenaTree[K, T]{edges: map[arenaEdge[K]]int32{}, free: arenaNil}
+	t.newNode(0, 0, false)
+	return t
+}
+
+// Insert inserts a key-value pair into the tree.
+// If the key already exists, its value is overwritten.
+// Returns the index of the node holding the key, or -1 if the key is empty.
+func (t *ArenaTree[K, T]) Insert(str []K, val T) int32 {
+	if len(str) == 0 {
+		return arenaNil
+	}
+	t.seq++
+	mark := int32(0)
+	index := 0
+	for index < len(str) {
+		cur := mark
+		next := t.getChild(cur, str[index])
+		if next == arenaNil {
+			// no match, add new node to current children
+			newNode := t.newLeaf(str[index:], val)
+			t.addChild(cur, newNode)
+			t.size++
+			t.refresh(cur, newNode)
+			return newNode
+		}
+		sharedPrefix := longestPrefix(t.textOf(next), str[index:])
+		if sharedPrefix < int(t.nodes[next].textLen) {
+			// partial match, split node; refresh fills in the common node's value
+			// the common node and next share next's original text fragment
+			commonNode := t.newNode(t.nodes[next].textOff, int32(sharedPrefix), false)
+			t.removeChild(cur, next)
+			t.addChild(cur, commonNode)
+			t.nodes[next].textOff += int32(sharedPrefix)
+			t.nodes[next].textLen -= int32(sharedPrefix)
+			t.addChild(commonNode, next)
+			if index+sharedPrefix < len(str) {
+				newNode := t.newLeaf(str[index+sharedPrefix:], val)
+				t.addChild(commonNode, newNode)
+				t.size++
+				t.refresh(commonNode, newNode)
+				return newNode
+			}
+			t.setVal(commonNode, val)
+			t.nodes[commonNode].end = true
+			t.size++
+			t.refresh(cur, commonNode)
+			return commonNode
+		}
+		// full match, move to next node
+		index += sharedPrefix
+		mark = next
+	}
+	if !t.nodes[mark].end {
+		t.size++
+	}
+	t.setVal(mark, val)
+	t.nodes[mark].end = true
+	t.refresh(t.nodes[mark].parent, mark)
+	return mark
+}
+
+// Len returns the number of keys stored in the tree in O(1).
+func (t *ArenaTree[K, T]) Len() int {
+	return t.size
+}
+
+// LongestCommonPrefixMatch finds the longest prefix in the tree that matches the given key.
+// It returns the longest common prefix, the value associated with the node where matching stopped
+// and whether that node has a value, and whether the match is exact, as Tree does.
+func (t *ArenaTree[K, T]) LongestCommonPrefixMatch(str []K) ([]K, T, bool, bool) {
+	length, val, ok, exact := t.LongestCommonPrefixLength(str)
+	return append([]K{}, str[:length]...), val, ok, exact
+}
+
+// LongestCommonPrefixLength is an allocation-free LongestCommonPrefixMatch: it returns the
+// length of the longest common prefix instead of a copy of it, which is always str[:length].
+func (t *ArenaTree[K, T]) LongestCommonPrefixLength(str []K) (int, T, bool, bool) {
+	mark := int32(0)
+	index := 0
+	for index < len(str) {
+		next := t.getChild(mark, str[index])
+		if next == arenaNil {
+			// no match，stop at current node
+			val, ok := t.value(mark)
+			return index, val, ok, false
+		}
+		mark = next
+		sharedPrefix := longestPrefix(t.textOf(next), str[index:])
+		if sharedPrefix < int(t.nodes[next].textLen) {
+			// partial match, stop
+			val, ok := t.value(mark)
+			return index + sharedPrefix, val, ok, false
+		}
+		// full match, move to next node
+		index += sharedPrefix
+	}
+	val, ok := t.value(mark)
+	return index, val, ok, t.nodes[mark].end
+}
+
+// Get returns the value stored under exactly the given key.
+func (t *ArenaTree[K, T]) Get(str []K) (T, bool) {
+	node := t.findNode(str)
+	if node == arenaNil || !t.nodes[node].end {
+		var zero T
+		return zero, false
+	}
+	return t.vals[node], true
+}
+
+// Delete removes exactly the given key from the tree through RemoveNode and returns its previous value.
+func (t *ArenaTree[K, T]) Delete(str []K) (T, bool) {
+	node := t.findNode(str)
+	if node == arenaNil || !t.nodes[node].end {
+		var zero T
+		return zero, false
+	}
+	old := t.vals[node]
+	t.RemoveNode(node)
+	return old, true
+}
+
+// findNode returns the index of the node whose full key equals str, or -1.
+func (t *ArenaTree[K, T]) findNode(str []K) int32 {
+	if len(str) == 0 {
+		return arenaNil
+	}
+	mark := int32(0)
+	index := 0
+	for index < len(str) {
+		next := t.getChild(mark, str[index])
+		if next == arenaNil {
+			return arenaNil
+		}
+		sharedPrefix := longestPrefix(t.textOf(next), str[index:])
+		if sharedPrefix < int(t.nodes[next].textLen) {
+			return arenaNil
+		}
+		index += sharedPrefix
+		mark = next
+	}
+	return mark
+}
+
+// RemoveNode removes the node at the given index from the tree, as Tree.RemoveNode does:
+// a node with children only stops being the end of a key, a leaf is unlinked and its slot
+// freed, a parent left without children and not holding a key is removed as well,
+// and an intermediate node left with a single child is merged into that child.
+// The root, indexes of free slots and out of range indexes are ignored.
+func (t *ArenaTree[K, T]) RemoveNode(node int32) {
+	if node <= 0 || int(node) >= len(t.nodes) || !t.nodes[node].live {
+		return
+	}
+	if t.nodes[node].child != arenaNil {
+		if t.nodes[node].end {
+			t.size--
+		}
+		t.nodes[node].end = false
+		t.tidy(node)
+		return
+	}
+	parent := t.nodes[node].parent
+	if t.nodes[node].end {
+		t.size--
+	}
+	t.removeChild(parent, node)
+	t.freeNode(node)
+	if t.nodes[parent].child == arenaNil && !t.nodes[parent].end {
+		t.RemoveNode(parent)
+	} else {
+		t.tidy(parent)
+	}
+}
+
+// tidy restores the invariants around a node whose children or End flag changed:
+// the node is compacted if it no longer branches, then the values above the change are refreshed.
+func (t *ArenaTree[K, T]) tidy(node int32) {
+	parent := t.nodes[node].parent
+	if t.compact(node) {
+		// merged into its only child, which keeps its own value
+		node = parent
+	}
+	t.refresh(node, arenaNil)
+}
+
+// refresh gives node and its intermediate ancestors the most recent value among their children,
+// stopping at the root, at an End node or as soon as a value is unchanged. inserted is the node
+// that just received the value of the current Insert, if any, which is the most recent value
+// of all; pass -1 after a removal.
+func (t *ArenaTree[K, T]) refresh(node, inserted int32) {
+	for ; node > 0 && !t.nodes[node].end; node = t.nodes[node].parent {
+		src := inserted
+		if src == arenaNil {
+			src = t.mostRecentChild(node)
+		}
+		n := &t.nodes[node]
+		if src == arenaNil {
+			if !n.hasVal {
+				return
+			}
+			var zero T
+			t.vals[node] = zero
+			n.hasVal, n.seq = false, 0
+			continue
+		}
+		if n.hasVal && n.seq == t.nodes[src].seq {
+			return
+		}
+		t.copyVal(node, src)
+	}
+}
+
+// mostRecentChild returns the child of node holding the most recently inserted value, or -1
+// if none of its children has a value.
+func (t *ArenaTree[K, T]) mostRecentChild(node int32) int32 {
+	latest := arenaNil
+	for child := t.nodes[node].child; child != arenaNil; child = t.nodes[child].next {
+		if t.nodes[child].hasVal && (latest == arenaNil || t.nodes[child].seq > t.nodes[latest].seq) {
+			latest = child
+		}
+	}
+	return latest
+}
+
+// compact merges an intermediate node left with a single child into that child, which takes over
+// the node's place with the concatenated text. The root and End nodes are never merged.
+// Reports whether the node was merged, in which case its slot is freed.
+func (t *ArenaTree[K, T]) compact(node int32) bool {
+	n := t.nodes[node]
+	if n.parent == arenaNil || n.end || n.child == arenaNil || t.nodes[n.child].next != arenaNil {
+		return false
+	}
+	parent, child := n.parent, n.child
+	c := t.nodes[child]
+	// unlink both before the child's text, and so its first element, changes
+	t.removeChild(node, child)
+	t.removeChild(parent, node)
+	if n.textOff+n.textLen == c.textOff {
+		// still adjacent since the split that created them, no copy needed
+		t.nodes[child].textOff = n.textOff
+	} else {
+		t.ensureText(int(n.textLen + c.textLen))
+		// ensureText may have moved the fragments
+		n, c = t.nodes[node], t.nodes[child]
+		off := int32(len(t.text))
+		t.text = append(t.text, t.text[n.textOff:n.textOff+n.textLen]...)
+		t.text = append(t.text, t.text[c.textOff:c.textOff+c.textLen]...)
+		t.garbage += int(n.textLen + c.textLen)
+		t.nodes[child].textOff = off
+	}
+	t.nodes[child].textLen = n.textLen + c.textLen
+	t.addChild(parent, child)
+	// the node's text now belongs to the child
+	t.nodes[node].textLen = 0
+	t.freeNode(node)
+	return true
+}
+
+// Walk calls fn for every stored key in the tree, along with its value.
+// Children are visited most recently added first. Iteration stops early when fn returns false.
+func (t *ArenaTree[K, T]) Walk(fn func(key []K, val T) bool) {
+	t.walk(0, []K{}, fn)
+}
+
+func (t *ArenaTree[K, T]) walk(node int32, parentKey []K, fn func(key []K, val T) bool) bool {
+	// limit capacity so that sibling keys never share a backing array
+	key := append(parentKey[:len(parentKey):len(parentKey)], t.textOf(node)...)
+	if t.nodes[node].end && !fn(key, t.vals[node]) {
+		return false
+	}
+	for child := t.nodes[node].child; child != arenaNil; child = t.nodes[child].next {
+		if !t.walk(child, key, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns a string representation of the tree structure in the same format as Tree.String.
+func (t *ArenaTree[K, T]) String() string {
+	var result strings.Builder
+	t.printNode(0, "", &result)
+	return result.String()
+}
+
+func (t *ArenaTree[K, T]) printNode(node int32, prefix string, result *strings.Builder) {
+	result.WriteString(prefix)
+	result.WriteString("└──")
+	result.WriteString(displayText(t.textOf(node)))
+
+	result.WriteString(" (val: ")
+	var val *T
+	if t.nodes[node].hasVal {
+		val = &t.vals[node]
+	}
+	result.WriteString(displayValue(val))
+	result.WriteString(")")
+	result.WriteString("\n")
+
+	children := []int32{}
+	for child := t.nodes[node].child; child != arenaNil; child = t.nodes[child].next {
+		children = append(children, child)
+	}
+	slices.SortFunc(children, func(a, b int32) int {
+		return compareStable(t.text[t.nodes[a].textOff], t.text[t.nodes[b].textOff])
+	})
+	newPrefix := prefix + "   "
+	for _, child := range children {
+		t.printNode(child, newPrefix, result)
+	}
+}
+
+// textOf returns the text fragment of a node as a capacity-limited view into the shared text.
+func (t *ArenaTree[K, T]) textOf(node int32) []K {
+	n := &t.nodes[node]
+	end := n.textOff + n.textLen
+	return t.text[n.textOff:end:end]
+}
+
+func (t *ArenaTree[K, T]) value(node int32) (T, bool) {
+	return t.vals[node], t.nodes[node].hasVal
+}
+
+// setVal stores the value of the current Insert in node.
+func (t *ArenaTree[K, T]) setVal(node int32, val T) {
+	t.vals[node] = val
+	t.nodes[node].hasVal = true
+	t.nodes[node].seq = t.seq
+}
+
+func (t *ArenaTree[K, T]) copyVal(dst, src int32) {
+	t.vals[dst] = t.vals[src]
+	t.nodes[dst].hasVal = t.nodes[src].hasVal
+	t.nodes[dst].seq = t.nodes[src].seq
+}
+
+// getChild returns the child of node whose text starts with head, or -1.
+func (t *ArenaTree[K, T]) getChild(node int32, head K) int32 {
+	if child, ok := t.edges[arenaEdge[K]{node, head}]; ok {
+		return child
+	}
+	return arenaNil
+}
+
+// addChild links child as the first child of parent. child must not be linked anywhere else.
+func (t *ArenaTree[K, T]) addChild(parent, child int32) {
+	t.edges[arenaEdge[K]{parent, t.text[t.nodes[child].textOff]}] = child
+	t.nodes[child].parent = parent
+	t.nodes[child].next = t.nodes[parent].child
+	t.nodes[parent].child = child
+}
+
+// removeChild unlinks child from the children of parent.
+func (t *ArenaTree[K, T]) removeChild(parent, child int32) {
+	delete(t.edges, arenaEdge[K]{parent, t.text[t.nodes[child].textOff]})
+	link := &t.nodes[parent].child
+	for *link != child {
+		link = &t.nodes[*link].next
+	}
+	*link = t.nodes[child].next
+	t.nodes[child].next = arenaNil
+	t.nodes[child].parent = arenaNil
+}
+
+// newNode takes a free slot, or appends one, for a node with the given text fragment.
+func (t *ArenaTree[K, T]) newNode(textOff, textLen int32, end bool) int32 {
+	node := arenaNode{
+		textOff: textOff,
+		textLen: textLen,
+		parent:  arenaNil,
+		child:   arenaNil,
+		next:    arenaNil,
+		end:     end,
+		live:    true,
+	}
+	if t.free != arenaNil {
+		slot := t.free
+		t.free = t.nodes[slot].next
+		t.nodes[slot] = node
+		return slot
+	}
+	if len(t.nodes) == math.MaxInt32 {
+		panic("lradix: arena tree node count exceeds int32 range")
+	}
+	t.nodes = append(t.nodes, node)
+	var zero T
+	t.vals = append(t.vals, zero)
+	return int32(len(t.nodes) - 1)
+}
+
+// newLeaf creates an unlinked End node holding a copy of text and val.
+func (t *ArenaTree[K, T]) newLeaf(text []K, val T) int32 {
+	t.ensureText(len(text))
+	off := int32(len(t.text))
+	t.text = append(t.text, text...)
+	node := t.newNode(off, int32(len(text)), true)
+	t.setVal(node, val)
+	return node
+}
+
+// freeNode releases the slot of an unlinked node to the free list.
+func (t *ArenaTree[K, T]) freeNode(node int32) {
+	t.garbage += int(t.nodes[node].textLen)
+	var zero T
+	t.vals[node] = zero
+	t.nodes[node] = arenaNode{parent: arenaNil, child: arenaNil, next: t.free}
+	t.free = node
+}
+
+// ensureText makes room for n more text elements, compacting the shared text array first
+// if more than half of it is garbage. Compaction moves fragments, so offsets read before
+// calling ensureText must be read again.
+func (t *ArenaTree[K, T]) ensureText(n int) {
+	if t.garbage > len(t.text)/2 {
+		t.compactText()
+	}
+	if len(t.text)+n > math.MaxInt32 {
+		panic("lradix: arena tree text exceeds int32 range")
+	}
+}
+
+// compactText copies the fragments of all live nodes into a new text array without garbage.
+func (t *ArenaTree[K, T]) compactText() {
+	text := make([]K, 0, len(t.text)-t.garbage)
+	for i := range t.nodes {
+		n := &t.nodes[i]
+		if !n.live {
+			continue
+		}
+		off := int32(len(text))
+		text = append(text, t.text[n.textOff:n.textOff+n.textLen]...)
+		n.textOff = off
+	}
+	t.text = text
+	t.garbage = 0
+}
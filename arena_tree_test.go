@@ -0,0 +1,235 @@
+package lradix
+
+import (
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+func TestArenaTree(t *testing.T) {
+	tree := NewArenaTree[byte, int]()
+	for i, key := range []string{"hello", "help", "world", "hi", "he"} {
+		tree.Insert([]byte(key), i)
+	}
+	if tree.Len() != 5 {
+		t.Errorf("Len() = %d, expected 5", tree.Len())
+	}
+
+	testCases := []struct {
+		query  string
+		prefix string
+		val    int
+		hasVal bool
+		exact  bool
+	}{
+		{"", "", 0, false, false},
+		{"hello", "hello", 0, true, true},
+		{"hello world", "hello", 0, true, false},
+		{"he", "he", 4, true, true},
+		{"hex", "he", 4, true, false},
+		{"world", "world", 2, true, true},
+		{"x", "", 0, false, false},
+	}
+	for _, tc := range testCases {
+		prefix, val, hasVal, exact := tree.LongestCommonPrefixMatch([]byte(tc.query))
+		if string(prefix) != tc.prefix || val != tc.val || hasVal != tc.hasVal || exact != tc.exact {
+			t.Errorf("LongestCommonPrefixMatch(%q) = %q, %d, %v, %v, expected %q, %d, %v, %v",
+				tc.query, prefix, val, hasVal, exact, tc.prefix, tc.val, tc.hasVal, tc.exact)
+		}
+	}
+
+	if val, ok := tree.Get([]byte("help")); !ok || val != 1 {
+		t.Errorf("Get(help) = %d, %v, expected 1, true", val, ok)
+	}
+	if _, ok := tree.Get([]byte("hel")); ok {
+		t.Error("Get(hel) found an intermediate node")
+	}
+	if val, ok := tree.Delete([]byte("help")); !ok || val != 1 {
+		t.Errorf("Delete(help) = %d, %v, expected 1, true", val, ok)
+	}
+	if _, ok := tree.Get([]byte("help")); ok {
+		t.Error("Get(help) found a deleted key")
+	}
+	if _, ok := tree.Delete([]byte("help")); ok {
+		t.Error("Delete(help) succeeded twice")
+	}
+	if tree.Len() != 4 {
+		t.Errorf("Len() = %d after Delete, expected 4", tree.Len())
+	}
+	if prefix, _, _, exact := tree.LongestCommonPrefixMatch([]byte("hello")); string(prefix) != "hello" || !exact {
+		t.Errorf("LongestCommonPrefixMatch(hello) = %q, %v after merging hel into hello", prefix, exact)
+	}
+}
+
+func TestArenaTreeMatchesTree(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	arena := NewArenaTree[byte, int]()
+	tree := NewTree[byte, int]()
+	keys := []string{}
+	for i := 0; i < 300; i++ {
+		key := randomKey(r)
+		keys = append(keys, key)
+		if arena.Insert([]byte(key), i) < 0 {
+			t.Fatalf("Insert(%q) returned no node", key)
+		}
+		tree.Insert([]byte(key), i)
+	}
+	// values propagate identically, so the layouts print the same
+	if arena.String() != tree.String() {
+		t.Fatalf("String() differs:\n%s\nexpected:\n%s", arena.String(), tree.String())
+	}
+
+	for i := 0; i < 3000; i++ {
+		key := []byte(keys[r.Intn(len(keys))])
+		switch r.Intn(3) {
+		case 0:
+			arena.Insert(key, i)
+			tree.Insert(key, i)
+		case 1:
+			_, arenaOK := arena.Delete(key)
+			_, treeOK := tree.Delete(key)
+			if arenaOK != treeOK {
+				t.Fatalf("Delete(%q) = %v, expected %v", key, arenaOK, treeOK)
+			}
+		}
+		query := append(key, "abc"[r.Intn(3)])
+		length, arenaVal, hasVal, exact := arena.LongestCommonPrefixLength(query)
+		prefix, val, treeExact := tree.LongestCommonPrefixMatch(query)
+		if length != len(prefix) || exact != treeExact || hasVal != (val != nil) || hasVal && arenaVal != *val {
+			t.Fatalf("step %d: LongestCommonPrefixLength(%q) = %d, %d, %v, %v, expected %d, %v, %v, %v",
+				i, query, length, arenaVal, hasVal, exact, len(prefix), displayValue(val), val != nil, treeExact)
+		}
+		if arena.String() != tree.String() {
+			t.Fatalf("step %d: String() differs:\n%s\nexpected:\n%s", i, arena.String(), tree.String())
+		}
+		if arena.Len() != tree.Len() {
+			t.Fatalf("step %d: Len() = %d, expected %d", i, arena.Len(), tree.Len())
+		}
+	}
+
+	count := 0
+	arena.Walk(func(key []byte, val int) bool {
+		if _, ok := tree.Get(key); !ok {
+			t.Errorf("Walk reported %q, which is not stored in the tree", key)
+		}
+		count++
+		return true
+	})
+	if count != tree.Len() {
+		t.Errorf("Walk visited %d keys, expected %d", count, tree.Len())
+	}
+}
+
+func TestArenaTreeReusesSlots(t *testing.T) {
+	tree := NewArenaTree[byte, int]()
+	keys := []string{"alpha", "alphabet", "alpine", "beta", "bet", "gamma"}
+	for round := 0; round < 100; round++ {
+		for i, key := range keys {
+			tree.Insert([]byte(key), i)
+		}
+		for _, key := range keys {
+			tree.Delete([]byte(key))
+		}
+	}
+	if tree.Len() != 0 {
+		t.Errorf("Len() = %d, expected 0", tree.Len())
+	}
+	// the busiest round needs the root plus at most two nodes per key
+	if len(tree.nodes) > 1+2*len(keys) {
+		t.Errorf("node slots grew to %d, expected free slots to be reused", len(tree.nodes))
+	}
+	total := 0
+	for _, key := range keys {
+		total += len(key)
+	}
+	if len(tree.text) > 4*total {
+		t.Errorf("text grew to %d elements, expected garbage to be compacted", len(tree.text))
+	}
+	if tree.RemoveNode(int32(len(tree.nodes))); tree.Len() != 0 {
+		t.Error("RemoveNode with an out of range index changed the tree")
+	}
+}
+
+func TestArenaTreeRemoveNode(t *testing.T) {
+	tree := NewArenaTree[byte, int]()
+	hello := tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2)
+	hel := tree.Insert([]byte("hel"), 3)
+
+	// a node with children only loses its End flag
+	tree.RemoveNode(hel)
+	if _, ok := tree.Get([]byte("hel")); ok {
+		t.Error("Get(hel) found a removed key")
+	}
+	if tree.Len() != 2 {
+		t.Errorf("Len() = %d, expected 2", tree.Len())
+	}
+
+	// removing help leaves hel with a single child, which is merged into hello
+	tree.Delete([]byte("help"))
+	if tree.nodes[0].child != hello || tree.nodes[hello].next != arenaNil {
+		t.Errorf("Expected hello to be the only child of the root:\n%s", tree.String())
+	}
+	if string(tree.textOf(hello)) != "hello" {
+		t.Errorf("merged text = %q, expected hello", tree.textOf(hello))
+	}
+	tree.RemoveNode(0)
+	if tree.Len() != 1 {
+		t.Error("RemoveNode removed the root")
+	}
+	if _, ok := tree.value(0); ok {
+		t.Error("RemoveNode(0) gave the root a value")
+	}
+}
+
+func TestArenaTreeSplitKeepsEndValue(t *testing.T) {
+	tree := NewArenaTree[byte, int]()
+	tree.Insert([]byte("a"), 1)
+	tree.Insert([]byte("abc"), 3)
+	tree.Insert([]byte("ab"), 2) // splits abc right below the End node a
+	for key, expected := range map[string]int{"a": 1, "ab": 2, "abc": 3} {
+		if val, ok := tree.Get([]byte(key)); !ok || val != expected {
+			t.Errorf("Get(%q) = %d, %v, expected %d, true", key, val, ok, expected)
+		}
+	}
+}
+
+func BenchmarkArenaTreeLongestCommonPrefixLength(b *testing.B) {
+	keys := benchmarkKeys[byte](50000, 256)
+	tree := NewArenaTree[byte, int]()
+	for i, key := range keys {
+		tree.Insert(key, i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.LongestCommonPrefixLength(keys[i%len(keys)])
+	}
+}
+
+// BenchmarkGC measures a full garbage collection with a large tree alive.
+func BenchmarkGC(b *testing.B) {
+	keys := benchmarkKeys[int32](500000, 32000)
+	b.Run("tree", func(b *testing.B) {
+		tree := NewTree[int32, int]()
+		for i, key := range keys {
+			tree.Insert(key, i)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			runtime.GC()
+		}
+		runtime.KeepAlive(tree)
+	})
+	b.Run("arena", func(b *testing.B) {
+		tree := NewArenaTree[int32, int]()
+		for i, key := range keys {
+			tree.Insert(key, i)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			runtime.GC()
+		}
+		runtime.KeepAlive(tree)
+	})
+}
@@ -0,0 +1,609 @@
+package lradix
+
+import (
+	"reflect"
+	"slices"
+)
+
+// childSet stores the children of a node indexed by the first element of their text.
+// A node without children holds no set at all. As children are added and removed, the set
+// moves between layouts: a single inline child, a small sorted slice and finally a map.
+// For byte keys the slice and map are replaced by ART-style layouts holding up to
+// 4, 16, 48 and 256 children. put and remove return the set to use from then on,
+// which may be a different layout.
+type childSet[K comparable, C comparable] interface {
+	get(head K) (C, bool)
+	put(head K, child C) childSet[K, C]
+	remove(head K) childSet[K, C]
+	len() int
+	// each calls fn for every child in the layout's order, which is sorted for every layout
+	// except the map. It returns false if fn stopped the iteration.
+	each(fn func(head K, child C) bool) bool
+	clone() childSet[K, C]
+	// sizeBytes estimates the heap footprint of the set itself, not of the children.
+	sizeBytes(keySize int64) int64
+}
+
+// children is the child storage embedded in nodes. The zero value holds no children.
+// It is not safe for concurrent use; ConcurrentNode guards it with its own lock.
+type children[K comparable, C comparable] struct {
+	set childSet[K, C]
+}
+
+func (c *children[K, C]) get(head K) (C, bool) {
+	if c.set == nil {
+		var zero C
+		return zero, false
+	}
+	return c.set.get(head)
+}
+
+func (c *children[K, C]) put(head K, child C) {
+	if c.set == nil {
+		c.set = &oneChild[K, C]{head: head, child: child}
+		return
+	}
+	c.set = c.set.put(head, child)
+}
+
+func (c *children[K, C]) remove(head K) {
+	if c.set != nil {
+		c.set = c.set.remove(head)
+	}
+}
+
+func (c *children[K, C]) len() int {
+	if c.set == nil {
+		return 0
+	}
+	return c.set.len()
+}
+
+func (c *children[K, C]) each(fn func(head K, child C) bool) bool {
+	if c.set == nil {
+		return true
+	}
+	return c.set.each(fn)
+}
+
+// first returns an arbitrary child, typically used when there is only one.
+func (c *children[K, C]) first() (C, bool) {
+	var first C
+	found := false
+	c.each(func(_ K, child C) bool {
+		first, found = child, true
+		return false
+	})
+	return first, found
+}
+
+func (c *children[K, C]) clone() children[K, C] {
+	if c.set == nil {
+		return children[K, C]{}
+	}
+	return children[K, C]{set: c.set.clone()}
+}
+
+func (c *children[K, C]) clear() {
+	c.set = nil
+}
+
+func (c *children[K, C]) sizeBytes(keySize int64) int64 {
+	if c.set == nil {
+		return 0
+	}
+	return c.set.sizeBytes(keySize)
+}
+
+// Layout thresholds. Shrinking happens below the growth point of the smaller layout,
+// so that a node hovering around a boundary does not flip layouts on every change.
+const (
+	maxSliceChildren = 8
+	maxArt4Children  = 4
+	maxArt16Children = 16
+	maxArt48Children = 48
+)
+
+// isByteKey reports whether K is byte, which enables the ART-style layouts.
+func isByteKey[K comparable]() bool {
+	var k K
+	_, ok := any(k).(byte)
+	return ok
+}
+
+func keyByte[K comparable](k K) byte {
+	return any(k).(byte)
+}
+
+func byteKey[K comparable](b byte) K {
+	return any(b).(K)
+}
+
+// structSize returns the size of the struct pointed to by p.
+func structSize(p any) int64 {
+	return int64(reflect.TypeOf(p).Elem().Size())
+}
+
+// oneChild stores a single child inline.
+type oneChild[K comparable, C comparable] struct {
+	head  K
+	child C
+}
+
+func (s *oneChild[K, C]) get(head K) (C, bool) {
+	if head == s.head {
+		return s.child, true
+	}
+	var zero C
+	return zero, false
+}
+
+func (s *oneChild[K, C]) put(head K, child C) childSet[K, C] {
+	if head == s.head {
+		s.child = child
+		return s
+	}
+	var grown childSet[K, C]
+	if isByteKey[K]() {
+		grown = &art4[K, C]{}
+	} else {
+		grown = &sliceChildren[K, C]{
+			heads:    make([]K, 0, 2),
+			children: make([]C, 0, 2),
+		}
+	}
+	return grown.put(s.head, s.child).put(head, child)
+}
+
+func (s *oneChild[K, C]) remove(head K) childSet[K, C] {
+	if head == s.head {
+		return nil
+	}
+	return s
+}
+
+func (s *oneChild[K, C]) len() int {
+	return 1
+}
+
+func (s *oneChild[K, C]) each(fn func(head K, child C) bool) bool {
+	return fn(s.head, s.child)
+}
+
+func (s *oneChild[K, C]) clone() childSet[K, C] {
+	cp := *s
+	return &cp
+}
+
+func (s *oneChild[K, C]) sizeBytes(keySize int64) int64 {
+	return structSize(s)
+}
+
+// sliceChildren stores up to maxSliceChildren children with heads sorted by compareStable.
+// Lookups scan the heads linearly, which beats hashing at this size.
+type sliceChildren[K comparable, C comparable] struct {
+	heads    []K
+	children []C
+}
+
+func (s *sliceChildren[K, C]) index(head K) int {
+	for i, h := range s.heads {
+		if h == head {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *sliceChildren[K, C]) get(head K) (C, bool) {
+	if i := s.index(head); i >= 0 {
+		return s.children[i], true
+	}
+	var zero C
+	return zero, false
+}
+
+func (s *sliceChildren[K, C]) put(head K, child C) childSet[K, C] {
+	if i := s.index(head); i >= 0 {
+		s.children[i] = child
+		return s
+	}
+	if len(s.heads) == maxSliceChildren {
+		m := make(mapChildren[K, C], len(s.heads)+1)
+		for i, h := range s.heads {
+			m[h] = s.children[i]
+		}
+		m[head] = child
+		return m
+	}
+	pos := 0
+	for pos < len(s.heads) && compareStable(s.heads[pos], head) < 0 {
+		pos++
+	}
+	s.heads = slices.Insert(s.heads, pos, head)
+	s.children = slices.Insert(s.children, pos, child)
+	return s
+}
+
+func (s *sliceChildren[K, C]) remove(head K) childSet[K, C] {
+	i := s.index(head)
+	if i < 0 {
+		return s
+	}
+	s.heads = slices.Delete(s.heads, i, i+1)
+	s.children = slices.Delete(s.children, i, i+1)
+	if len(s.heads) == 1 {
+		return &oneChild[K, C]{head: s.heads[0], child: s.children[0]}
+	}
+	return s
+}
+
+func (s *sliceChildren[K, C]) len() int {
+	return len(s.heads)
+}
+
+func (s *sliceChildren[K, C]) each(fn func(head K, child C) bool) bool {
+	for i, h := range s.heads {
+		if !fn(h, s.children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *sliceChildren[K, C]) clone() childSet[K, C] {
+	return &sliceChildren[K, C]{heads: slices.Clone(s.heads), children: slices.Clone(s.children)}
+}
+
+func (s *sliceChildren[K, C]) sizeBytes(keySize int64) int64 {
+	return structSize(s) + int64(cap(s.heads))*keySize + int64(cap(s.children))*8
+}
+
+// mapChildren stores any number of children in a map.
+type mapChildren[K comparable, C comparable] map[K]C
+
+func (m mapChildren[K, C]) get(head K) (C, bool) {
+	child, ok := m[head]
+	return child, ok
+}
+
+func (m mapChildren[K, C]) put(head K, child C) childSet[K, C] {
+	m[head] = child
+	return m
+}
+
+func (m mapChildren[K, C]) remove(head K) childSet[K, C] {
+	delete(m, head)
+	if len(m) > maxSliceChildren/2 {
+		return m
+	}
+	s := &sliceChildren[K, C]{}
+	for h, child := range m {
+		s.put(h, child)
+	}
+	return s
+}
+
+func (m mapChildren[K, C]) len() int {
+	return len(m)
+}
+
+func (m mapChildren[K, C]) each(fn func(head K, child C) bool) bool {
+	for h, child := range m {
+		if !fn(h, child) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m mapChildren[K, C]) clone() childSet[K, C] {
+	cp := make(mapChildren[K, C], len(m))
+	for h, child := range m {
+		cp[h] = child
+	}
+	return cp
+}
+
+func (m mapChildren[K, C]) sizeBytes(keySize int64) int64 {
+	return estimateMapBytes(len(m), keySize)
+}
+
+// art4 stores up to 4 children of a byte-keyed node with sorted keys.
+type art4[K comparable, C comparable] struct {
+	n        uint8
+	keys     [maxArt4Children]byte
+	children [maxArt4Children]C
+}
+
+func (s *art4[K, C]) get(head K) (C, bool) {
+	b := keyByte(head)
+	for i := 0; i < int(s.n); i++ {
+		if s.keys[i] == b {
+			return s.children[i], true
+		}
+	}
+	var zero C
+	return zero, false
+}
+
+func (s *art4[K, C]) put(head K, child C) childSet[K, C] {
+	if artPut(s.keys[:], s.children[:], &s.n, keyByte(head), child) {
+		return s
+	}
+	grown := &art16[K, C]{n: s.n}
+	copy(grown.keys[:], s.keys[:])
+	copy(grown.children[:], s.children[:])
+	return grown.put(head, child)
+}
+
+func (s *art4[K, C]) remove(head K) childSet[K, C] {
+	artRemove(s.keys[:], s.children[:], &s.n, keyByte(head))
+	if s.n == 1 {
+		return &oneChild[K, C]{head: byteKey[K](s.keys[0]), child: s.children[0]}
+	}
+	return s
+}
+
+func (s *art4[K, C]) len() int {
+	return int(s.n)
+}
+
+func (s *art4[K, C]) each(fn func(head K, child C) bool) bool {
+	return artEach(s.keys[:s.n], s.children[:s.n], fn)
+}
+
+func (s *art4[K, C]) clone() childSet[K, C] {
+	cp := *s
+	return &cp
+}
+
+func (s *art4[K, C]) sizeBytes(keySize int64) int64 {
+	return structSize(s)
+}
+
+// art16 stores up to 16 children of a byte-keyed node with sorted keys.
+type art16[K comparable, C comparable] struct {
+	n        uint8
+	keys     [maxArt16Children]byte
+	children [maxArt16Children]C
+}
+
+func (s *art16[K, C]) get(head K) (C, bool) {
+	b := keyByte(head)
+	if i, ok := slices.BinarySearch(s.keys[:s.n], b); ok {
+		return s.children[i], true
+	}
+	var zero C
+	return zero, false
+}
+
+func (s *art16[K, C]) put(head K, child C) childSet[K, C] {
+	if artPut(s.keys[:], s.children[:], &s.n, keyByte(head), child) {
+		return s
+	}
+	grown := &art48[K, C]{}
+	for i := 0; i < int(s.n); i++ {
+		grown.put(byteKey[K](s.keys[i]), s.children[i])
+	}
+	return grown.put(head, child)
+}
+
+func (s *art16[K, C]) remove(head K) childSet[K, C] {
+	artRemove(s.keys[:], s.children[:], &s.n, keyByte(head))
+	if s.n >= maxArt4Children {
+		return s
+	}
+	shrunk := &art4[K, C]{n: s.n}
+	copy(shrunk.keys[:], s.keys[:s.n])
+	copy(shrunk.children[:], s.children[:s.n])
+	return shrunk
+}
+
+func (s *art16[K, C]) len() int {
+	return int(s.n)
+}
+
+func (s *art16[K, C]) each(fn func(head K, child C) bool) bool {
+	return artEach(s.keys[:s.n], s.children[:s.n], fn)
+}
+
+func (s *art16[K, C]) clone() childSet[K, C] {
+	cp := *s
+	return &cp
+}
+
+func (s *art16[K, C]) sizeBytes(keySize int64) int64 {
+	return structSize(s)
+}
+
+// artPut stores child under b in the sorted keys and children of an art4 or art16 holding n children.
+// It returns false if b is new and the arrays are full.
+func artPut[C any](keys []byte, children []C, n *uint8, b byte, child C) bool {
+	i, found := slices.BinarySearch(keys[:*n], b)
+	if found {
+		children[i] = child
+		return true
+	}
+	if int(*n) == len(keys) {
+		return false
+	}
+	copy(keys[i+1:*n+1], keys[i:*n])
+	copy(children[i+1:*n+1], children[i:*n])
+	keys[i] = b
+	children[i] = child
+	*n++
+	return true
+}
+
+// artRemove deletes b from the sorted keys and children of an art4 or art16 holding n children.
+func artRemove[C any](keys []byte, children []C, n *uint8, b byte) {
+	i, found := slices.BinarySearch(keys[:*n], b)
+	if !found {
+		return
+	}
+	copy(keys[i:], keys[i+1:*n])
+	copy(children[i:], children[i+1:*n])
+	*n--
+	var zero C
+	children[*n] = zero
+}
+
+func artEach[K comparable, C any](keys []byte, children []C, fn func(head K, child C) bool) bool {
+	for i, b := range keys {
+		if !fn(byteKey[K](b), children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// art48 stores up to 48 children of a byte-keyed node. index maps a key to its slot plus one,
+// zero meaning absent; slots are kept dense by moving the last one into a freed slot.
+type art48[K comparable, C comparable] struct {
+	n        uint8
+	index    [256]uint8
+	keys     [maxArt48Children]byte
+	children [maxArt48Children]C
+}
+
+func (s *art48[K, C]) get(head K) (C, bool) {
+	if slot := s.index[keyByte(head)]; slot != 0 {
+		return s.children[slot-1], true
+	}
+	var zero C
+	return zero, false
+}
+
+func (s *art48[K, C]) put(head K, child C) childSet[K, C] {
+	b := keyByte(head)
+	if slot := s.index[b]; slot != 0 {
+		s.children[slot-1] = child
+		return s
+	}
+	if s.n == maxArt48Children {
+		grown := &art256[K, C]{}
+		for i := 0; i < int(s.n); i++ {
+			grown.put(byteKey[K](s.keys[i]), s.children[i])
+		}
+		return grown.put(head, child)
+	}
+	s.keys[s.n] = b
+	s.children[s.n] = child
+	s.n++
+	s.index[b] = s.n
+	return s
+}
+
+func (s *art48[K, C]) remove(head K) childSet[K, C] {
+	b := keyByte(head)
+	slot := s.index[b]
+	if slot == 0 {
+		return s
+	}
+	s.index[b] = 0
+	last := s.n - 1
+	if slot-1 != last {
+		s.keys[slot-1] = s.keys[last]
+		s.children[slot-1] = s.children[last]
+		s.index[s.keys[last]] = slot
+	}
+	var zero C
+	s.children[last] = zero
+	s.n--
+	if s.n > maxArt16Children*3/4 {
+		return s
+	}
+	shrunk := &art16[K, C]{}
+	s.each(func(head K, child C) bool {
+		shrunk.put(head, child)
+		return true
+	})
+	return shrunk
+}
+
+func (s *art48[K, C]) len() int {
+	return int(s.n)
+}
+
+func (s *art48[K, C]) each(fn func(head K, child C) bool) bool {
+	for b, slot := range s.index {
+		if slot != 0 && !fn(byteKey[K](byte(b)), s.children[slot-1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *art48[K, C]) clone() childSet[K, C] {
+	cp := *s
+	return &cp
+}
+
+func (s *art48[K, C]) sizeBytes(keySize int64) int64 {
+	return structSize(s)
+}
+
+// art256 stores the children of a byte-keyed node directly indexed by key.
+type art256[K comparable, C comparable] struct {
+	n        int
+	children [256]C
+}
+
+func (s *art256[K, C]) get(head K) (C, bool) {
+	child := s.children[keyByte(head)]
+	var zero C
+	return child, child != zero
+}
+
+func (s *art256[K, C]) put(head K, child C) childSet[K, C] {
+	b := keyByte(head)
+	var zero C
+	if s.children[b] == zero {
+		s.n++
+	}
+	s.children[b] = child
+	return s
+}
+
+func (s *art256[K, C]) remove(head K) childSet[K, C] {
+	b := keyByte(head)
+	var zero C
+	if s.children[b] == zero {
+		return s
+	}
+	s.children[b] = zero
+	s.n--
+	if s.n > maxArt48Children*3/4 {
+		return s
+	}
+	shrunk := &art48[K, C]{}
+	s.each(func(head K, child C) bool {
+		shrunk.put(head, child)
+		return true
+	})
+	return shrunk
+}
+
+func (s *art256[K, C]) len() int {
+	return s.n
+}
+
+func (s *art256[K, C]) each(fn func(head K, child C) bool) bool {
+	var zero C
+	for b, child := range s.children {
+		if child != zero && !fn(byteKey[K](byte(b)), child) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *art256[K, C]) clone() childSet[K, C] {
+	cp := *s
+	return &cp
+}
+
+func (s *art256[K, C]) sizeBytes(keySize int64) int64 {
+	return structSize(s)
+}
@@ -0,0 +1,314 @@
+package lradix
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// childOf returns the child of node indexed by head, or nil.
+func childOf[K comparable, T any](node *Node[K, T], head K) *Node[K, T] {
+	child, _ := node.GetChild(head)
+	return child
+}
+
+// layoutName returns the name of the layout currently used by c.
+func layoutName[K comparable, C comparable](c *children[K, C]) string {
+	switch c.set.(type) {
+	case nil:
+		return "empty"
+	case *oneChild[K, C]:
+		return "one"
+	case *sliceChildren[K, C]:
+		return "slice"
+	case mapChildren[K, C]:
+		return "map"
+	case *art4[K, C]:
+		return "art4"
+	case *art16[K, C]:
+		return "art16"
+	case *art48[K, C]:
+		return "art48"
+	case *art256[K, C]:
+		return "art256"
+	}
+	return fmt.Sprintf("%T", c.set)
+}
+
+func TestChildrenLayouts(t *testing.T) {
+	t.Run("byte", func(t *testing.T) {
+		testCases := []struct {
+			n      int
+			layout string
+		}{
+			{0, "empty"},
+			{1, "one"},
+			{2, "art4"},
+			{4, "art4"},
+			{5, "art16"},
+			{16, "art16"},
+			{17, "art48"},
+			{48, "art48"},
+			{49, "art256"},
+			{256, "art256"},
+		}
+		for _, tc := range testCases {
+			c := &children[byte, *int]{}
+			for _, b := range rand.New(rand.NewSource(int64(tc.n))).Perm(256)[:tc.n] {
+				v := b
+				c.put(byte(b), &v)
+			}
+			if got := layoutName(c); got != tc.layout {
+				t.Errorf("%d children: layout %s, expected %s", tc.n, got, tc.layout)
+			}
+			checkChildren(t, c, tc.n)
+		}
+	})
+
+	t.Run("string", func(t *testing.T) {
+		testCases := []struct {
+			n      int
+			layout string
+		}{
+			{1, "one"},
+			{2, "slice"},
+			{maxSliceChildren, "slice"},
+			{maxSliceChildren + 1, "map"},
+		}
+		for _, tc := range testCases {
+			c := &children[string, *int]{}
+			for i := tc.n - 1; i >= 0; i-- {
+				v := i
+				c.put(fmt.Sprintf("k%02d", i), &v)
+			}
+			if got := layoutName(c); got != tc.layout {
+				t.Errorf("%d children: layout %s, expected %s", tc.n, got, tc.layout)
+			}
+			if tc.layout != "map" {
+				heads := childOrder(c, nil)
+				if !slices.IsSorted(heads) {
+					t.Errorf("%d children: storage order %v is not sorted", tc.n, heads)
+				}
+			}
+		}
+	})
+
+	t.Run("shrink", func(t *testing.T) {
+		c := &children[byte, *int]{}
+		for i := 0; i < 256; i++ {
+			v := i
+			c.put(byte(i), &v)
+		}
+		for i := 255; i >= 0; i-- {
+			c.remove(byte(i))
+			checkChildren(t, c, i)
+		}
+		if got := layoutName(c); got != "empty" {
+			t.Errorf("layout %s after removing every child, expected empty", got)
+		}
+	})
+}
+
+// checkChildren verifies that c holds n children whose values equal their byte head, in sorted order.
+func checkChildren(t *testing.T, c *children[byte, *int], n int) {
+	t.Helper()
+	if c.len() != n {
+		t.Errorf("len() = %d, expected %d", c.len(), n)
+	}
+	count := 0
+	last := -1
+	c.each(func(head byte, child *int) bool {
+		if *child != int(head) {
+			t.Errorf("child under %d holds %d", head, *child)
+		}
+		if int(head) <= last {
+			t.Errorf("head %d visited after %d", head, last)
+		}
+		last = int(head)
+		if got, ok := c.get(head); !ok || got != child {
+			t.Errorf("get(%d) = %v, %v", head, got, ok)
+		}
+		count++
+		return true
+	})
+	if count != n {
+		t.Errorf("each visited %d children, expected %d", count, n)
+	}
+}
+
+func TestChildrenRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	c := &children[byte, *int]{}
+	s := &children[string, *int]{}
+	expected := map[byte]*int{}
+	for i := 0; i < 20000; i++ {
+		b := byte(r.Intn(256))
+		if r.Intn(2) == 0 {
+			v := i
+			c.put(b, &v)
+			s.put(string(rune(b)), &v)
+			expected[b] = &v
+		} else {
+			c.remove(b)
+			s.remove(string(rune(b)))
+			delete(expected, b)
+		}
+		if c.len() != len(expected) || s.len() != len(expected) {
+			t.Fatalf("step %d: len() = %d and %d, expected %d", i, c.len(), s.len(), len(expected))
+		}
+	}
+	for b, v := range expected {
+		if got, ok := c.get(b); !ok || got != v {
+			t.Errorf("byte get(%d) = %v, %v, expected %v", b, got, ok, v)
+		}
+		if got, ok := s.get(string(rune(b))); !ok || got != v {
+			t.Errorf("string get(%q) = %v, %v, expected %v", string(rune(b)), got, ok, v)
+		}
+	}
+	clone := c.clone()
+	c.clear()
+	if clone.len() != len(expected) {
+		t.Errorf("clone len() = %d, expected %d", clone.len(), len(expected))
+	}
+}
+
+func TestNodeChildrenAPI(t *testing.T) {
+	tree := NewTree[byte, int]()
+	for i, key := range []string{"apple", "banana", "cherry"} {
+		tree.Insert([]byte(key), i)
+	}
+	if tree.Root.NumChildren() != 3 {
+		t.Errorf("NumChildren() = %d, expected 3", tree.Root.NumChildren())
+	}
+	heads := []byte{}
+	tree.Root.ForEachChild(func(head byte, child *Node[byte, int]) bool {
+		if child.Text[0] != head || child.Parent != tree.Root {
+			t.Errorf("child %q is not linked under %q", child.Text, head)
+		}
+		heads = append(heads, head)
+		return true
+	})
+	if string(heads) != "abc" {
+		t.Errorf("ForEachChild visited %q, expected abc", heads)
+	}
+	tree.Root.RemoveChild('b')
+	if _, ok := tree.Root.GetChild('b'); ok || tree.Root.NumChildren() != 2 {
+		t.Errorf("RemoveChild('b') left %d children", tree.Root.NumChildren())
+	}
+}
+
+// mapOnly replaces the child storage of every node below node with a map,
+// reproducing the layout used before adaptive child storage.
+func mapOnly[K comparable, T any](node *Node[K, T]) {
+	m := mapChildren[K, *Node[K, T]]{}
+	node.children.each(func(head K, child *Node[K, T]) bool {
+		m[head] = child
+		mapOnly(child)
+		return true
+	})
+	node.children.set = m
+}
+
+// benchmarkKeys returns n random token sequences sharing prefixes, like tokenized prompts.
+func benchmarkKeys[K byte | int32](n int, alphabet int) [][]K {
+	r := rand.New(rand.NewSource(1))
+	prefixes := make([][]K, 64)
+	for i := range prefixes {
+		prefixes[i] = make([]K, 4+r.Intn(12))
+		for j := range prefixes[i] {
+			prefixes[i][j] = K(r.Intn(alphabet))
+		}
+	}
+	keys := make([][]K, n)
+	for i := range keys {
+		key := slices.Clone(prefixes[r.Intn(len(prefixes))])
+		for j := 0; j < 4+r.Intn(24); j++ {
+			key = append(key, K(r.Intn(alphabet)))
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+func buildBenchmarkTree[K byte | int32](keys [][]K, layout string) *Tree[K, int] {
+	tree := NewTree[K, int]()
+	for i, key := range keys {
+		tree.Insert(key, i)
+	}
+	if layout == "map" {
+		mapOnly(tree.Root)
+	}
+	return tree
+}
+
+func BenchmarkTreeMemory(b *testing.B) {
+	for _, layout := range []string{"adaptive", "map"} {
+		b.Run(layout+"/byte", func(b *testing.B) {
+			benchmarkTreeMemory(b, benchmarkKeys[byte](50000, 256), layout)
+		})
+		b.Run(layout+"/int32", func(b *testing.B) {
+			benchmarkTreeMemory(b, benchmarkKeys[int32](50000, 32000), layout)
+		})
+	}
+}
+
+func benchmarkTreeMemory[K byte | int32](b *testing.B, keys [][]K, layout string) {
+	var stats runtime.MemStats
+	var heap uint64
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		before := stats.HeapAlloc
+		tree := buildBenchmarkTree(keys, layout)
+		runtime.GC()
+		runtime.ReadMemStats(&stats)
+		heap = stats.HeapAlloc - before
+		runtime.KeepAlive(tree)
+	}
+	b.ReportMetric(float64(heap)/float64(len(keys)), "heap-B/key")
+}
+
+func BenchmarkTreeLongestCommonPrefixMatch(b *testing.B) {
+	for _, layout := range []string{"adaptive", "map"} {
+		b.Run(layout+"/byte", func(b *testing.B) {
+			benchmarkTreeLookup(b, benchmarkKeys[byte](50000, 256), layout)
+		})
+		b.Run(layout+"/int32", func(b *testing.B) {
+			benchmarkTreeLookup(b, benchmarkKeys[int32](50000, 32000), layout)
+		})
+	}
+}
+
+func benchmarkTreeLookup[K byte | int32](b *testing.B, keys [][]K, layout string) {
+	tree := buildBenchmarkTree(keys, layout)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.LongestCommonPrefixMatch(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkChildrenGet(b *testing.B) {
+	for _, n := range []int{1, 4, 16, 48, 256} {
+		heads := rand.New(rand.NewSource(1)).Perm(256)[:n]
+		adaptive := &children[byte, *int]{}
+		m := map[byte]*int{}
+		for _, h := range heads {
+			v := h
+			adaptive.put(byte(h), &v)
+			m[byte(h)] = &v
+		}
+		b.Run(fmt.Sprintf("adaptive/n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				adaptive.get(byte(heads[i%n]))
+			}
+		})
+		b.Run(fmt.Sprintf("map/n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = m[byte(heads[i%n])]
+			}
+		})
+	}
+}
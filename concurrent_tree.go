@@ -1,10 +1,11 @@
 package lradix
 
 import (
-	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 var nodeNumber atomic.Int64
@@ -28,23 +29,65 @@ func NewMatch[T any](id int64, l int, v *T, exact bool) Match[T] {
 // ConcurrentNode represents a thread-safe node in the radix tree.
 // It contains a read-write mutex for concurrent access, text fragment of type K,
 // associated value of type T, and child nodes. The mutex ensures thread-safe
-// operations on the node's data.
+// operations on the node's data. Children are stored adaptively, as in Node.
 type ConcurrentNode[K comparable, T any] struct {
 	sync.RWMutex
 	ID       int64
-	Text     []K                         // Text fragment for this node (of comparable type K)
-	Val      *T                          // Value associated with this node (nil for intermediate nodes, of type T)
-	End      bool                        // Whether this node represents the end of a complete key
-	Children map[K]*ConcurrentNode[K, T] // Child nodes indexed by first character (key type K)
-	Parent   *ConcurrentNode[K, T]       // Parent node for tree traversal
+	Text     []K                                // Text fragment for this node (of comparable type K)
+	Val      *T                                 // Value associated with this node (derived by the ValuePolicy for intermediate nodes, of type T)
+	End      bool                               // Whether this node represents the end of a complete key
+	Parent   *ConcurrentNode[K, T]              // Parent node for tree traversal
+	children children[K, *ConcurrentNode[K, T]] // Child nodes indexed by first character (key type K)
+	seq      uint64                             // insertion sequence number of Val, used by the value policy
+	summary  any                                // cached Aggregator summary of the subtree, or the owner counts of an OwnerTree
+	access   atomic.Uint64                      // last access time, only tracked by trees created with a capacity
+	expires  int64                              // expiry time of Val in Unix nanoseconds, zero if it never expires
+	pins     int                                // number of Pin handles holding this node, changed under t.mu.RLock, see Pin
+	// position in the tree's LRU heap plus one, zero unless the node is an evictable leaf
+	lruSlot   atomic.Int64
+	lruAccess uint64 // access time ordering the LRU heap, guarded by the tree's lruMu
 }
 
 // GetChild retrieves a child node by its first character (type K).
 // Returns the child node and a boolean indicating if it was found.
 // Note: the caller is responsible for acquiring the necessary locks before calling this method.
 func (n *ConcurrentNode[K, T]) GetChild(head K) (*ConcurrentNode[K, T], bool) {
-	child, ok := n.Children[head]
-	return child, ok
+	return n.children.get(head)
+}
+
+// RemoveChild removes the child indexed by the given first character (type K), if any.
+// Note: the caller is responsible for acquiring the necessary locks before calling this method.
+func (n *ConcurrentNode[K, T]) RemoveChild(head K) {
+	n.children.remove(head)
+}
+
+// NumChildren returns the number of child nodes.
+// Note: the caller is responsible for acquiring the necessary locks before calling this method.
+func (n *ConcurrentNode[K, T]) NumChildren() int {
+	return n.children.len()
+}
+
+// ForEachChild calls fn for every child node along with the first character indexing it,
+// in storage order. Iteration stops early when fn returns false.
+// Note: the caller is responsible for acquiring the necessary locks before calling this method.
+func (n *ConcurrentNode[K, T]) ForEachChild(fn func(head K, child *ConcurrentNode[K, T]) bool) {
+	n.children.each(fn)
+}
+
+// Children returns the child nodes indexed by their first character (type K), as the former
+// Children field of the node did. Replacing the field with this method is a breaking change:
+// code using n.Children must call n.Children() instead. The map is built on every call;
+// modifying it has no effect on the tree, so writers must use AddChild and RemoveChild.
+// Note: the caller is responsible for acquiring the necessary locks before calling this method.
+//
+// Deprecated: use GetChild, NumChildren and ForEachChild, which do not allocate.
+func (n *ConcurrentNode[K, T]) Children() map[K]*ConcurrentNode[K, T] {
+	children := make(map[K]*ConcurrentNode[K, T], n.children.len())
+	n.children.each(func(head K, child *ConcurrentNode[K, T]) bool {
+		children[head] = child
+		return true
+	})
+	return children
 }
 
 // AddChild adds a child node to this node.
@@ -55,23 +98,19 @@ func (n *ConcurrentNode[K, T]) AddChild(node *ConcurrentNode[K, T]) {
 	if len(node.Text) == 0 {
 		return
 	}
-	if n.Children == nil {
-		n.Children = map[K]*ConcurrentNode[K, T]{}
-	}
 	node.Parent = n
-	n.Children[node.Text[0]] = node
+	n.children.put(node.Text[0], node)
 }
 
 // NewConcurrentNode creates a new concurrent node with the given text (type K), value (type T), and end flag.
-// The node is initialized with an empty children map and is ready for concurrent operations.
+// The node is initialized without children and is ready for concurrent operations.
 // The end flag determines whether this node represents the end of a complete key.
 func NewConcurrentNode[K comparable, T any](text []K, val *T, end bool) *ConcurrentNode[K, T] {
 	return &ConcurrentNode[K, T]{
-		ID:       nodeNumber.Add(1),
-		Text:     text,
-		Val:      val,
-		End:      end,
-		Children: map[K]*ConcurrentNode[K, T]{},
+		ID:   nodeNumber.Add(1),
+		Text: text,
+		Val:  val,
+		End:  end,
 	}
 }
 
@@ -81,65 +120,176 @@ func NewConcurrentNode[K comparable, T any](text []K, val *T, end bool) *Concurr
 // fine-grained locking to maximize concurrency.
 type ConcurrentTree[K comparable, T any] struct {
 	Root *ConcurrentNode[K, T] // Root node of the tree
+	size atomic.Int64          // number of stored keys, maintained by Insert and RemoveNode
+	// number of key elements in the text of all nodes, maintained by Insert and RemoveNode
+	elements atomic.Int64
+	seq      atomic.Uint64 // sequence number of the last Insert
+	opts     treeOptions
+	// values derives the values of intermediate nodes, see ValuePolicy
+	values valuePolicy[T]
+	agg    aggregator[T] // maintains subtree summaries, nil unless created WithAggregator
+
+	clock   atomic.Uint64         // logical clock of node accesses, see WithMaxKeys
+	evictMu sync.Mutex            // serializes evictions
+	onEvict func(key []K, val *T) // reports evicted entries, see WithEvictCallback
+	lruMu   sync.Mutex            // guards lru and the lruAccess of the nodes in it
+	lru     lruHeap[K, T]         // evictable leaves of a bounded tree, least recently used first
+
+	expiring atomic.Bool // set once a value has been inserted with a TTL, so lookups check expiry times
+	janitor  janitor     // background removal of expired entries, see Start
+
+	// mu is read-locked by every structural write and by Pin and Unpin so that writers still run
+	// concurrently under node locks, and write-locked by Snapshot to pause all writers at once.
+	mu sync.RWMutex
 }
 
 // NewConcurrentTree creates a new empty concurrent radix tree with keys of type K and values of type T.
 // The tree is initialized with a root node and is ready for concurrent operations.
-func NewConcurrentTree[K comparable, T any]() *ConcurrentTree[K, T] {
-	return &ConcurrentTree[K, T]{
-		Root: NewConcurrentNode[K, T]([]K{}, nil, false),
+// By default Insert copies key fragments into the tree; see WithZeroCopy.
+// Intermediate nodes carry the most recently inserted value below them; see WithValuePolicy.
+func NewConcurrentTree[K comparable, T any](opts ...Option) *ConcurrentTree[K, T] {
+	o := newTreeOptions(opts)
+	t := &ConcurrentTree[K, T]{
+		Root:    NewConcurrentNode[K, T]([]K{}, nil, false),
+		opts:    o,
+		values:  newValuePolicy[T](o),
+		agg:     newAggregator[T](o),
+		onEvict: newEvictCallback[K, T](o),
 	}
+	t.summarize(t.Root)
+	return t
 }
 
 // Insert inserts a key-value pair into the tree in a thread-safe manner.
 // The key is represented as a slice of type K, and the value is of type T.
 // If the key already exists, it will be overwritten.
+// The key is copied unless the tree was created WithZeroCopy, so the caller may reuse it afterwards.
 // This method uses fine-grained locking to ensure thread safety while maximizing concurrency.
+// In a tree created with a capacity, the least recently used leaves are evicted afterwards
+// if the tree has grown beyond it; see WithMaxKeys. The value expires after the tree's
+// default TTL, if one was set WithTTL; see InsertWithTTL.
 // Returns the newly created node or nil if insertion failed.
 func (t *ConcurrentTree[K, T]) Insert(str []K, val T) *ConcurrentNode[K, T] {
+	return t.put(str, t.opts.ttl, overwrite(val))
+}
+
+// overwrite returns the decision of Insert, which stores val whether or not the key exists.
+func overwrite[T any](val T) func(old *T, exists bool) (*T, bool) {
+	return func(*T, bool) (*T, bool) {
+		return &val, true
+	}
+}
+
+// put implements the writes that store a value under a key. decide is called once, with the
+// lock of the node that would hold the value, and returns the value to store, if any; see insert.
+// It returns the node holding the stored value, or nil if decide stored nothing.
+func (t *ConcurrentTree[K, T]) put(str []K, ttl time.Duration, decide func(old *T, exists bool) (*T, bool)) *ConcurrentNode[K, T] {
 	if len(str) == 0 {
 		return nil
 	}
+	expires := t.expiryTime(ttl)
+	t.mu.RLock()
+	seq := t.seq.Add(1)
+	var node *ConcurrentNode[K, T]
+	for {
+		n, leaf, ok := t.insert(str, decide, seq, expires)
+		if ok {
+			node = n
+			if node == nil {
+				break
+			}
+			node.RLock()
+			parent := node.Parent
+			node.RUnlock()
+			t.refresh(parent, node, seq, leaf)
+			t.summarize(node)
+			break
+		}
+		// a node on the path was removed or merged away concurrently, retry from the root
+	}
+	removed := t.evict()
+	t.mu.RUnlock()
+	t.notifyEvicted(removed)
+	return node
+}
+
+// insert implements put and reports whether the value was stored in a new leaf.
+// decide is called with the current value of the key and whether it exists, where expired
+// values count as absent, and returns the value to store or false to leave the tree unchanged,
+// in which case the returned node is nil. It runs while the node that holds or would hold the
+// key is locked, along with its parent when the key is absent, so the decision is atomic.
+// The value expires at expires, in Unix nanoseconds, unless it is zero.
+// It returns false without modifying the tree if it reaches a node that has been
+// detached from the tree since it was looked up.
+func (t *ConcurrentTree[K, T]) insert(str []K, decide func(old *T, exists bool) (*T, bool), seq uint64, expires int64) (*ConcurrentNode[K, T], bool, bool) {
+	now := t.tick()
+	clock := t.expiryClock()
 	mark := t.Root
 	index := 0
 	for index < len(str) {
 		cur := mark
 		char := str[index]
 		cur.Lock() // ===🟧===
+		if cur != t.Root && cur.Parent == nil {
+			cur.Unlock() // ===🟠===
+			return nil, false, false
+		}
 		next, ok := cur.GetChild(char)
 		if !ok {
 			// no match, add new node to current children
-			newNode := NewConcurrentNode(str[index:], &val, false)
+			val, store := decide(nil, false)
+			if !store {
+				cur.Unlock() // ===🟠===
+				return nil, false, true
+			}
+			newNode := NewConcurrentNode(ownText(t.opts, str[index:]), val, true)
+			newNode.seq, newNode.expires = seq, expires
+			t.touch(newNode, now)
 			cur.AddChild(newNode)
+			t.size.Add(1)
+			t.elements.Add(int64(len(newNode.Text)))
+			// newNode can't be reached before cur is unlocked
+			t.trackLocked(newNode)
+			t.trackLocked(cur)
 			cur.Unlock() // ===🟠===
-			return newNode
+			return newNode, true, true
 		}
 		next.Lock() // ===🟦===
 		sharedPrefix := longestPrefix(next.Text, str[index:])
 		if sharedPrefix < len(next.Text) {
-			// partial match, split node
-			// use this insert val as common node val, because it is most recent
-			commonNode := NewConcurrentNode(next.Text[:sharedPrefix], &val, false)
-			cur.AddChild(commonNode)
-			if cur.Parent != nil {
-				// if not root, update parent val
-				cur.Val = &val
+			// partial match, split node; the value policy fills in the common node's value
+			val, store := decide(nil, false)
+			if !store {
+				cur.Unlock()  // ===🟠===
+				next.Unlock() // ===🔵===
+				return nil, false, true
 			}
+			commonNode := NewConcurrentNode[K, T](next.Text[:sharedPrefix], nil, false)
+			t.touch(commonNode, now)
+			cur.AddChild(commonNode)
 			next.Text = next.Text[sharedPrefix:]
 			commonNode.AddChild(next)
 			if index+sharedPrefix < len(str) {
-				newNode := NewConcurrentNode(str[index+sharedPrefix:], &val, true)
+				newNode := NewConcurrentNode(ownText(t.opts, str[index+sharedPrefix:]), val, true)
+				newNode.seq, newNode.expires = seq, expires
+				t.touch(newNode, now)
 				commonNode.AddChild(newNode)
+				t.size.Add(1)
+				t.elements.Add(int64(len(newNode.Text)))
+				t.trackLocked(newNode)
 				cur.Unlock()  // ===🟠===
 				next.Unlock() // ===🔵===
-				return newNode
+				return newNode, true, true
 			} else {
+				commonNode.Val, commonNode.seq, commonNode.expires = val, seq, expires
 				commonNode.End = true
+				t.size.Add(1)
 				cur.Unlock()  // ===🟠===
 				next.Unlock() // ===🔵===
-				return commonNode
+				return commonNode, false, true
 			}
 		}
+		t.touch(next, now)
 		cur.Unlock()  // ===🟠===
 		next.Unlock() // ===🔵===
 		// full match, move to next node
@@ -147,10 +297,34 @@ func (t *ConcurrentTree[K, T]) Insert(str []K, val T) *ConcurrentNode[K, T] {
 		mark = next
 	}
 	mark.Lock()
-	mark.Val = &val
+	if mark.Parent == nil {
+		mark.Unlock()
+		return nil, false, false
+	}
+	exists := mark.End && !expired(mark.expires, clock)
+	var old *T
+	if exists {
+		old = mark.Val
+	}
+	val, store := decide(old, exists)
+	if !store {
+		mark.Unlock()
+		return nil, false, true
+	}
+	if !mark.End {
+		t.size.Add(1)
+	}
+	mark.Val, mark.seq, mark.expires = val, seq, expires
 	mark.End = true
+	t.trackLocked(mark)
 	mark.Unlock()
-	return mark
+	return mark, false, true
+}
+
+// Len returns the number of keys stored in the tree in O(1).
+// It counts keys added and removed through the tree's methods.
+func (t *ConcurrentTree[K, T]) Len() int {
+	return int(t.size.Load())
 }
 
 // LongestCommonPrefixMatch finds the longest prefix in the tree that matches the given key.
@@ -158,7 +332,17 @@ func (t *ConcurrentTree[K, T]) Insert(str []K, val T) *ConcurrentNode[K, T] {
 // and a boolean indicating whether it is an exact match. This operation is thread-safe and uses
 // read locks to allow concurrent reads while ensuring data consistency.
 func (t *ConcurrentTree[K, T]) LongestCommonPrefixMatch(str []K) (int64, []K, *T, bool) {
-	commonPrefix := []K{}
+	id, length, val, exact := t.LongestCommonPrefixLength(str)
+	return id, append([]K{}, str[:length]...), val, exact
+}
+
+// LongestCommonPrefixLength is an allocation-free LongestCommonPrefixMatch: it returns the
+// length of the longest common prefix instead of a copy of it, which is always str[:length].
+// The node ID, value and exact flag are the same as those of LongestCommonPrefixMatch.
+// Expired values are reported as nil, and a key whose value has expired is never an exact match.
+func (t *ConcurrentTree[K, T]) LongestCommonPrefixLength(str []K) (int64, int, *T, bool) {
+	now := t.tick()
+	clock := t.expiryClock()
 	mark := t.Root
 	var id int64
 	index := 0
@@ -168,33 +352,75 @@ func (t *ConcurrentTree[K, T]) LongestCommonPrefixMatch(str []K) (int64, []K, *T
 		// no match，stop at current node
 		cur.RLock()
 		next, ok := cur.GetChild(char)
-		val := cur.Val
+		val := liveVal(cur, clock)
 		id = cur.ID
 		cur.RUnlock()
 		if !ok {
-			return id, commonPrefix, val, false
+			return id, index, val, false
 		}
 		mark = next
+		t.touch(next, now)
 		next.RLock()
 		matchText := next.Text
-		matchVal := next.Val
+		matchVal := liveVal(next, clock)
 		id = next.ID
 		next.RUnlock()
 		sharedPrefix := longestPrefix(matchText, str[index:])
-		commonPrefix = append(commonPrefix, matchText[:sharedPrefix]...)
 		if sharedPrefix < len(matchText) {
 			// partial match, stop
-			return id, commonPrefix, matchVal, false
+			return id, index + sharedPrefix, matchVal, false
 		}
 		// full match, move to next node
 		index += sharedPrefix
 	}
 	mark.RLock()
 	defer mark.RUnlock()
-	return mark.ID, commonPrefix, mark.Val, mark.End
+	return mark.ID, index, liveVal(mark, clock), mark.End && !expired(mark.expires, clock)
+}
+
+// LongestMatchFunc is Tree.LongestMatchFunc for a ConcurrentTree, which also returns the ID of
+// the accepted node, or that of the root if no value is accepted. Expired values count as nil.
+// Nodes are read-locked one at a time and no lock is held while accept runs.
+func (t *ConcurrentTree[K, T]) LongestMatchFunc(str []K, accept func(val *T) bool) (int64, int, *T, bool) {
+	now := t.tick()
+	clock := t.expiryClock()
+	mark := t.Root
+	id, length, val, exact := mark.ID, 0, (*T)(nil), false
+	index := 0
+	for index < len(str) {
+		mark.RLock()
+		next, ok := mark.GetChild(str[index])
+		mark.RUnlock()
+		if !ok {
+			break
+		}
+		t.touch(next, now)
+		next.RLock()
+		sharedPrefix := longestPrefix(next.Text, str[index:])
+		partial := sharedPrefix < len(next.Text)
+		nextVal := liveVal(next, clock)
+		end := next.End
+		next.RUnlock()
+		index += sharedPrefix
+		if nextVal != nil && accept(nextVal) {
+			id, length, val, exact = next.ID, index, nextVal, end && !partial && index == len(str)
+		}
+		if partial {
+			// partial match, stop
+			break
+		}
+		mark = next
+	}
+	return id, length, val, exact
 }
 
+// MultiLongestCommonPrefixMatch returns every candidate node along the path of the given key:
+// each node passed on the way, the node where matching stopped, and that node's children.
+// Children are reported in stable order so the result is deterministic for a given tree.
+// Expired values are reported as nil, as in LongestCommonPrefixLength.
 func (t *ConcurrentTree[K, T]) MultiLongestCommonPrefixMatch(str []K) []Match[T] {
+	now := t.tick()
+	clock := t.expiryClock()
 	candidates := []Match[T]{}
 	mark := t.Root
 	var id int64
@@ -205,24 +431,26 @@ func (t *ConcurrentTree[K, T]) MultiLongestCommonPrefixMatch(str []K) []Match[T]
 		// no match，stop at current node
 		cur.RLock()
 		next, ok := cur.GetChild(char)
-		val := cur.Val
+		val := liveVal(cur, clock)
 		id = cur.ID
 		candidates = append(candidates, NewMatch(id, index, val, false))
 		cur.RUnlock()
 		if !ok {
 			cur.RLock()
-			for _, child := range cur.Children {
+			eachChild(&cur.children, compareStable[K], func(child *ConcurrentNode[K, T]) bool {
 				child.RLock()
-				candidates = append(candidates, NewMatch(child.ID, index, child.Val, false))
+				candidates = append(candidates, NewMatch(child.ID, index, liveVal(child, clock), false))
 				child.RUnlock()
-			}
+				return true
+			})
 			cur.RUnlock()
 			return candidates
 		}
 		mark = next
+		t.touch(next, now)
 		next.RLock()
 		matchText := next.Text
-		matchVal := next.Val
+		matchVal := liveVal(next, clock)
 		id = next.ID
 		next.RUnlock()
 		sharedPrefixLength := longestPrefix(matchText, str[index:])
@@ -230,9 +458,12 @@ func (t *ConcurrentTree[K, T]) MultiLongestCommonPrefixMatch(str []K) []Match[T]
 			// partial match, stop
 			candidates = append(candidates, NewMatch(id, index+sharedPrefixLength, matchVal, false))
 			next.RLock()
-			for _, child := range next.Children {
-				candidates = append(candidates, NewMatch(child.ID, index+sharedPrefixLength, child.Val, false))
-			}
+			eachChild(&next.children, compareStable[K], func(child *ConcurrentNode[K, T]) bool {
+				child.RLock()
+				candidates = append(candidates, NewMatch(child.ID, index+sharedPrefixLength, liveVal(child, clock), false))
+				child.RUnlock()
+				return true
+			})
 			next.RUnlock()
 			return candidates
 		}
@@ -241,25 +472,273 @@ func (t *ConcurrentTree[K, T]) MultiLongestCommonPrefixMatch(str []K) []Match[T]
 	}
 	mark.RLock()
 	defer mark.RUnlock()
-	candidates = append(candidates, NewMatch(mark.ID, index, mark.Val, mark.End))
-	for _, child := range mark.Children {
-		candidates = append(candidates, NewMatch(child.ID, index, child.Val, false))
-	}
+	candidates = append(candidates, NewMatch(mark.ID, index, liveVal(mark, clock), mark.End && !expired(mark.expires, clock)))
+	eachChild(&mark.children, compareStable[K], func(child *ConcurrentNode[K, T]) bool {
+		child.RLock()
+		candidates = append(candidates, NewMatch(child.ID, index, liveVal(child, clock), false))
+		child.RUnlock()
+		return true
+	})
 	return candidates
 }
 
+// Get returns the value stored under exactly the given key in a thread-safe manner.
+// Only nodes that represent the end of a complete key are reported; partial matches
+// and intermediate nodes return nil and false, as do keys whose value has expired.
+func (t *ConcurrentTree[K, T]) Get(str []K) (*T, bool) {
+	node := t.findNode(str)
+	if node == nil {
+		return nil, false
+	}
+	node.RLock()
+	defer node.RUnlock()
+	if !node.End || expired(node.expires, t.expiryClock()) {
+		return nil, false
+	}
+	return node.Val, true
+}
+
+// Delete removes exactly the given key from the tree and returns its previous value.
+// The node is located by key and removed as by RemoveNode, so the same parent cleanup
+// and locking apply. The value is read under the same locks that remove the key, so
+// concurrent Deletes of one key report its removal only once.
+// Returns nil and false if the key is not stored in the tree, and also if it is pinned;
+// use TryDelete to tell the two apart.
+func (t *ConcurrentTree[K, T]) Delete(str []K) (*T, bool) {
+	old, removed, _ := t.TryDelete(str)
+	return old, removed
+}
+
+// TryDelete is Delete, but returns ErrPinned if the key is stored and held by a Pin handle,
+// as RemoveNode does. It returns nil, false and a nil error if the key is not stored.
+func (t *ConcurrentTree[K, T]) TryDelete(str []K) (*T, bool, error) {
+	var old *T
+	removed, err := t.deleteIf(str, func(node *ConcurrentNode[K, T]) bool {
+		old = node.Val
+		return node.End
+	})
+	if !removed {
+		return nil, false, err
+	}
+	return old, true, nil
+}
+
+// deleteIf removes the key str if cond holds for its node, and reports whether it did.
+// cond is called with the node and its parent locked. The lookup is retried when the node
+// is detached or merged away before it could be locked. ErrPinned is returned if cond holds
+// but the node is pinned.
+func (t *ConcurrentTree[K, T]) deleteIf(str []K, cond func(node *ConcurrentNode[K, T]) bool) (bool, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for {
+		node := t.findNode(str)
+		if node == nil {
+			return false, nil
+		}
+		checked := false
+		removed, err := t.removeNode(node, func(node *ConcurrentNode[K, T]) bool {
+			checked = true
+			return cond(node)
+		})
+		if removed || checked || err != nil {
+			return removed, err
+		}
+		// the node was removed or merged away concurrently, look the key up again
+	}
+}
+
+// findNode returns the node whose full key equals str, or nil if the key ends
+// inside a node's text or leaves the tree. The returned node may be intermediate.
+// Each node is read-locked only while it is inspected.
+func (t *ConcurrentTree[K, T]) findNode(str []K) *ConcurrentNode[K, T] {
+	if len(str) == 0 {
+		return nil
+	}
+	mark := t.Root
+	index := 0
+	for index < len(str) {
+		mark.RLock()
+		next, ok := mark.GetChild(str[index])
+		mark.RUnlock()
+		if !ok {
+			return nil
+		}
+		next.RLock()
+		matchText := next.Text
+		next.RUnlock()
+		sharedPrefix := longestPrefix(matchText, str[index:])
+		if sharedPrefix < len(matchText) {
+			return nil
+		}
+		index += sharedPrefix
+		mark = next
+	}
+	return mark
+}
+
+// WalkPrefix calls fn for every stored key that starts with the given prefix, along with its value.
+// The prefix may end inside a node's text; all keys below that node are still visited.
+// Keys whose values have expired are skipped, as in Get.
+// Nodes are read-locked one at a time and no lock is held while fn runs, so fn may modify
+// the tree, but the walk is not a consistent snapshot under concurrent writes.
+// Iteration stops early when fn returns false.
+func (t *ConcurrentTree[K, T]) WalkPrefix(prefix []K, fn func(key []K, val *T) bool) {
+	mark := t.Root
+	key := []K{}
+	index := 0
+	for index < len(prefix) {
+		mark.RLock()
+		next, ok := mark.GetChild(prefix[index])
+		mark.RUnlock()
+		if !ok {
+			return
+		}
+		next.RLock()
+		matchText := next.Text
+		next.RUnlock()
+		sharedPrefix := longestPrefix(matchText, prefix[index:])
+		if sharedPrefix < len(matchText) && index+sharedPrefix < len(prefix) {
+			// diverged inside the node, nothing below can match
+			return
+		}
+		key = append(key, matchText...)
+		index += sharedPrefix
+		mark = next
+	}
+	walkConcurrentNode(mark, key, nil, t.expiryClock(), fn)
+}
+
+// Walk calls fn for every stored key in the tree, along with its value.
+// Children are visited in storage order; use WalkConcurrentOrdered for a deterministic, sorted walk.
+// The same locking rules as WalkPrefix apply. Iteration stops early when fn returns false.
+func (t *ConcurrentTree[K, T]) Walk(fn func(key []K, val *T) bool) {
+	walkConcurrentNode(t.Root, []K{}, nil, t.expiryClock(), fn)
+}
+
+// KeysWithPrefix returns every stored key that starts with the given prefix.
+func (t *ConcurrentTree[K, T]) KeysWithPrefix(prefix []K) [][]K {
+	keys := [][]K{}
+	t.WalkPrefix(prefix, func(key []K, _ *T) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// WalkPath calls fn for every stored key that is a prefix of str, from shortest to longest.
+// Only nodes that represent the end of a complete key are reported, and expired keys are skipped as in Get. The key passed to fn
+// is a capacity-limited subslice of str. No lock is held while fn runs.
+// Iteration stops early when fn returns false.
+func (t *ConcurrentTree[K, T]) WalkPath(str []K, fn func(key []K, val *T) bool) {
+	t.walkPath(str, func(node *ConcurrentNode[K, T], length int, val *T) bool {
+		return fn(str[:length:length], val)
+	})
+}
+
+// AllPrefixMatches returns every stored key that is a prefix of str, from shortest to longest,
+// as matches carrying the node ID, prefix length and value. Exact is set for the match covering all of str.
+func (t *ConcurrentTree[K, T]) AllPrefixMatches(str []K) []Match[T] {
+	matches := []Match[T]{}
+	t.walkPath(str, func(node *ConcurrentNode[K, T], length int, val *T) bool {
+		matches = append(matches, NewMatch(node.ID, length, val, length == len(str)))
+		return true
+	})
+	return matches
+}
+
+// walkPath descends along str and calls fn for every End node whose full key is a prefix of str
+// and whose value has not expired.
+// Each node is read-locked only while it is inspected; fn is called without holding any lock.
+func (t *ConcurrentTree[K, T]) walkPath(str []K, fn func(node *ConcurrentNode[K, T], length int, val *T) bool) {
+	clock := t.expiryClock()
+	mark := t.Root
+	index := 0
+	for index < len(str) {
+		mark.RLock()
+		next, ok := mark.GetChild(str[index])
+		mark.RUnlock()
+		if !ok {
+			return
+		}
+		next.RLock()
+		matchText := next.Text
+		end := next.End && !expired(next.expires, clock)
+		val := next.Val
+		next.RUnlock()
+		sharedPrefix := longestPrefix(matchText, str[index:])
+		if sharedPrefix < len(matchText) {
+			return
+		}
+		index += sharedPrefix
+		mark = next
+		if end && !fn(next, index, val) {
+			return
+		}
+	}
+}
+
+// walkConcurrentNode visits node and its descendants depth-first, calling fn for every End node
+// whose value has not expired at clock, as returned by expiryClock.
+// key is the reconstructed key of node, including its own text.
+// The node is read-locked only while its fields and children are copied out; the children's
+// texts are read under the same lock, so a child merged with its parent concurrently still
+// gets a correct key. Children are visited in the order given by compare, or in storage order if compare is nil.
+func walkConcurrentNode[K comparable, T any](node *ConcurrentNode[K, T], key []K, compare func(a, b K) int, clock int64, fn func(key []K, val *T) bool) bool {
+	type childKey struct {
+		node *ConcurrentNode[K, T]
+		key  []K
+	}
+	node.RLock()
+	end := node.End && !expired(node.expires, clock)
+	val := node.Val
+	children := make([]childKey, 0, node.children.len())
+	eachChild(&node.children, compare, func(child *ConcurrentNode[K, T]) bool {
+		child.RLock()
+		// limit capacity so that sibling keys never share a backing array
+		children = append(children, childKey{child, append(key[:len(key):len(key)], child.Text...)})
+		child.RUnlock()
+		return true
+	})
+	node.RUnlock()
+	if end && !fn(key, val) {
+		return false
+	}
+	for _, child := range children {
+		if !walkConcurrentNode(child.node, child.key, compare, clock, fn) {
+			return false
+		}
+	}
+	return true
+}
+
 // RemoveNode removes a node from the tree in a thread-safe manner.
 // Only leaf nodes (nodes without children) can be removed.
 // When a leaf node is removed, its parent may also be removed if it becomes
 // an intermediate node with no children and doesn't represent a complete key.
+// An intermediate node left with a single child is merged into that child.
 // This method uses proper locking to ensure thread safety during the removal process.
-func (t *ConcurrentTree[K, T]) RemoveNode(node *ConcurrentNode[K, T]) {
+// A node pinned by Pin is left untouched and ErrPinned is returned.
+func (t *ConcurrentTree[K, T]) RemoveNode(node *ConcurrentNode[K, T]) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, err := t.removeNode(node, nil)
+	return err
+}
+
+// removeNode implements RemoveNode and reports whether it removed the key stored in node.
+// If cond is not nil, the node is only removed if cond returns true; it is called with
+// the node and its parent locked, so the condition still holds when the node is removed.
+// The caller must hold t.mu.RLock, which is not re-acquired by the recursive calls.
+func (t *ConcurrentTree[K, T]) removeNode(node *ConcurrentNode[K, T], cond func(node *ConcurrentNode[K, T]) bool) (bool, error) {
 	node.RLock()
 	parent := node.Parent
 	node.RUnlock()
 	if parent == nil {
-		// root node can't be removed
-		return
+		// root node can't be removed, but it may have lost its last child
+		if node == t.Root {
+			t.summarize(node)
+		}
+		return false, nil
 	}
 	parent.Lock() // ===🟦===
 	node.Lock()   // ===🟧===
@@ -267,34 +746,239 @@ func (t *ConcurrentTree[K, T]) RemoveNode(node *ConcurrentNode[K, T]) {
 		node.Unlock()
 		parent.Unlock()
 		// parent changed, retry
-		t.RemoveNode(node)
-		return
+		return t.removeNode(node, cond)
+	}
+	if cond != nil && !cond(node) {
+		node.Unlock()
+		parent.Unlock()
+		return false, nil
+	}
+	if node.pins > 0 {
+		node.Unlock()
+		parent.Unlock()
+		return false, ErrPinned
 	}
-	if len(node.Children) > 0 {
-		for _, v := range node.Children {
-			node.Val = v.Val
+	removed := node.End
+	if node.children.len() > 0 {
+		if node.End {
+			t.size.Add(-1)
 		}
 		node.End = false
 		node.Unlock()   // ===🟠===
 		parent.Unlock() // ===🔵===
-		return
+		t.tidy(node)
+		return removed, nil
 	}
 	node.Parent = nil
 	nodeKey := node.Text[0]
+	if node.End {
+		t.size.Add(-1)
+	}
+	t.elements.Add(-int64(len(node.Text)))
+	t.trackLocked(node)
 	node.Unlock() // ===🟠===
-	delete(parent.Children, nodeKey)
-	if len(parent.Children) == 0 && !parent.End {
+	parent.children.remove(nodeKey)
+	t.trackLocked(parent)
+	if parent.children.len() == 0 && !parent.End && parent.pins == 0 {
 		parent.Unlock() // ===🔵=== must unlock before recursive call Remove
-		t.RemoveNode(parent)
+		t.removeNode(parent, nil)
 	} else {
-		if parent.Parent != nil {
-			for _, v := range parent.Children {
-				parent.Val = v.Val
-				break
+		// a pinned parent is kept even without children until it is unpinned
+		parent.Unlock() // ===🔵===
+		t.tidy(parent)
+	}
+	return removed, nil
+}
+
+// tidy restores the invariants around a node whose children or End flag changed:
+// the node is compacted if it no longer branches, then the values above the change are refreshed.
+// The caller must hold t.mu.RLock and no node lock.
+func (t *ConcurrentTree[K, T]) tidy(node *ConcurrentNode[K, T]) {
+	node.RLock()
+	parent := node.Parent
+	node.RUnlock()
+	t.compact(node)
+	node.RLock()
+	merged := parent != nil && node.Parent == nil
+	node.RUnlock()
+	if merged {
+		// merged into its only child, which keeps its own value
+		node = parent
+	}
+	t.refresh(node, nil, 0, false)
+	t.summarize(node)
+}
+
+// compact merges an intermediate node that is left with a single child into that child,
+// so that every intermediate node keeps branching. The root, End nodes and pinned nodes are never merged.
+// Locks are taken top-down (parent, node, child) and the conditions are re-checked under them.
+// The caller must hold t.mu.RLock.
+func (t *ConcurrentTree[K, T]) compact(node *ConcurrentNode[K, T]) {
+	node.RLock()
+	parent := node.Parent
+	node.RUnlock()
+	if parent == nil {
+		return
+	}
+	parent.Lock() // ===🟦===
+	node.Lock()   // ===🟧===
+	if node.Parent != parent {
+		node.Unlock()
+		parent.Unlock()
+		// parent changed, retry
+		t.compact(node)
+		return
+	}
+	if node.End || node.children.len() != 1 || node.pins > 0 {
+		node.Unlock()   // ===🟠===
+		parent.Unlock() // ===🔵===
+		return
+	}
+	child, _ := node.children.first()
+	child.Lock()
+	child.Text = concatText(node.Text, child.Text)
+	parent.AddChild(child)
+	child.Unlock()
+	node.Parent = nil
+	node.children.clear()
+	node.Unlock()   // ===🟠===
+	parent.Unlock() // ===🔵===
+}
+
+// refresh updates the value of node and of its intermediate ancestors according to the
+// value policy, stopping at the root, at an End node or as soon as a value is unchanged.
+// from is the child of node that just received the value with sequence number seq, if any,
+// which lets the built-in policies skip re-reading the other children, and leaf tells whether
+// it is a new leaf; pass nil after a removal. The shortcut is only taken while from is still
+// linked under node with that value, so a concurrent removal is never undone. Each node is
+// locked on its own while its children are read-locked, so locks are still taken parent before child.
+func (t *ConcurrentTree[K, T]) refresh(node *ConcurrentNode[K, T], from *ConcurrentNode[K, T], seq uint64, leaf bool) {
+	for node != nil {
+		node.Lock()
+		if node.Parent == nil || node.End {
+			// the root, a detached node or a node holding its own value
+			node.Unlock()
+			return
+		}
+		var inserted *T
+		var insertedExpires int64
+		if from != nil {
+			from.RLock()
+			if from.Parent == node && from.seq == seq {
+				inserted, insertedExpires = from.Val, from.expires
 			}
+			from.RUnlock()
 		}
-		parent.Unlock() // ===🔵===
+		skip, insertedWins := false, false
+		if inserted != nil {
+			skip, insertedWins = t.values.skipOnInsert(node.Val, node.seq, seq, leaf)
+		}
+		changed := true
+		switch {
+		case skip:
+			changed = false
+		case insertedWins:
+			node.Val, node.seq, node.expires = inserted, seq, insertedExpires
+		default:
+			acc := valueAcc[T]{policy: &t.values}
+			var expires int64
+			node.children.each(func(_ K, child *ConcurrentNode[K, T]) bool {
+				child.RLock()
+				acc.add(child.Val, child.seq)
+				if acc.val == child.Val && acc.seq == child.seq {
+					// the child's value is the node's value so far, which expires along with it
+					expires = child.expires
+				}
+				child.RUnlock()
+				return true
+			})
+			val, valSeq := acc.result()
+			if t.values.kind == ValueReduce {
+				// a reduced value is not stored under any key and never expires
+				expires = 0
+			}
+			changed = val != node.Val || valSeq != node.seq || expires != node.expires
+			node.Val, node.seq, node.expires = val, valSeq, expires
+		}
+		parent := node.Parent
+		node.Unlock()
+		if !changed {
+			return
+		}
+		if inserted == nil {
+			from = nil
+		} else {
+			from = node
+		}
+		node = parent
+	}
+}
+
+// summarize recomputes the cached subtree summaries of node and all of its ancestors.
+// Like refresh, each node is locked on its own while its children are read-locked, and every
+// write summarizes its path after the change, so the last update to reach a node sees all of
+// its children's changes. The walk stops at a node that has been detached concurrently, whose
+// remover summarizes the remaining path itself.
+func (t *ConcurrentTree[K, T]) summarize(node *ConcurrentNode[K, T]) {
+	if t.agg == nil {
+		return
+	}
+	for node != nil {
+		node.Lock()
+		if node != t.Root && node.Parent == nil {
+			node.Unlock()
+			return
+		}
+		summary := t.agg.own(node.End, node.Val)
+		node.children.each(func(_ K, child *ConcurrentNode[K, T]) bool {
+			child.RLock()
+			summary = t.agg.combine(summary, child.summary)
+			child.RUnlock()
+			return true
+		})
+		node.summary = summary
+		parent := node.Parent
+		node.Unlock()
+		node = parent
+	}
+}
+
+// Snapshot returns a point-in-time, read-only copy of the tree as a Tree.
+// The node structure and key fragments are deep copied while values are shared.
+// Writers are paused for the duration of the copy, so the result reflects a single
+// consistent state; readers are not blocked. Keys whose values have expired are left out,
+// as if RemoveExpired had run.
+func (t *ConcurrentTree[K, T]) Snapshot() *Tree[K, T] {
+	clock := t.expiryClock()
+	var stale []*Node[K, T]
+	t.mu.Lock()
+	tree := &Tree[K, T]{Root: snapshotNode(t.Root, clock, &stale), size: t.Len(), seq: t.seq.Load(), opts: t.opts, values: t.values, agg: t.agg}
+	t.mu.Unlock()
+	for _, node := range stale {
+		tree.RemoveNode(node)
 	}
+	return tree
+}
+
+// snapshotNode recursively copies a concurrent node and its children into a plain node.
+// Copies of End nodes whose values have expired at clock are appended to stale.
+// The caller must hold t.mu.Lock, so no node can be modified during the copy.
+func snapshotNode[K comparable, T any](node *ConcurrentNode[K, T], clock int64, stale *[]*Node[K, T]) *Node[K, T] {
+	nc := &Node[K, T]{
+		Text:    slices.Clone(node.Text),
+		Val:     node.Val,
+		End:     node.End,
+		seq:     node.seq,
+		summary: node.summary,
+	}
+	if node.End && expired(node.expires, clock) {
+		*stale = append(*stale, nc)
+	}
+	node.children.each(func(_ K, child *ConcurrentNode[K, T]) bool {
+		nc.AddChild(snapshotNode(child, clock, stale))
+		return true
+	})
+	return nc
 }
 
 // String returns a string representation of the tree structure.
@@ -316,35 +1000,18 @@ func printConcurrentNode[K comparable, T any](node *ConcurrentNode[K, T], prefix
 	node.RLock()
 	defer node.RUnlock()
 
-	var displayText string
-	if len(node.Text) == 0 {
-		displayText = "ROOT"
-	} else {
-		switch v := any(node.Text).(type) {
-		case []byte:
-			displayText = string(v)
-		case []rune:
-			displayText = string(v)
-		default:
-			displayText = fmt.Sprintf("%v", node.Text)
-		}
-	}
-
 	result.WriteString(prefix)
 	result.WriteString("└──")
-	result.WriteString(displayText)
+	result.WriteString(displayText(node.Text))
 
 	result.WriteString(" (val: ")
-	if node.Val == nil {
-		result.WriteString("nil")
-	} else {
-		result.WriteString(fmt.Sprintf("%v", *node.Val))
-	}
+	result.WriteString(displayValue(node.Val))
 	result.WriteString(")")
 	result.WriteString("\n")
 
 	newPrefix := prefix + "   "
-	for _, child := range node.Children {
+	eachChild(&node.children, compareStable[K], func(child *ConcurrentNode[K, T]) bool {
 		printConcurrentNode(child, newPrefix, result)
-	}
+		return true
+	})
 }
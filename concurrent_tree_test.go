@@ -2,9 +2,12 @@ package lradix
 
 import (
 	"fmt"
+	"math/rand"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestConcurrentTreeBasicUsage(t *testing.T) {
@@ -86,7 +89,7 @@ func TestAllMatches(t *testing.T) {
 		input  string
 		expect []int
 	}{
-		{"help!", []int{0, 2, 4}},
+		{"help!", []int{0, 4, 4}},
 	}
 
 	for _, tc := range testCases {
@@ -327,3 +330,404 @@ func TestConcurrentWriteReadRemove(t *testing.T) {
 		}
 	}
 }
+
+func TestConcurrentTreeGetDelete(t *testing.T) {
+	tree := NewConcurrentTree[rune, int]()
+	tree.Insert([]rune("hello"), 1)
+	tree.Insert([]rune("help"), 2)
+	tree.Insert([]rune("helper"), 3)
+
+	testCases := []struct {
+		input    string
+		expected int
+		found    bool
+	}{
+		{"hello", 1, true},
+		{"help", 2, true},
+		{"helper", 3, true},
+		{"hel", 0, false},
+		{"helpe", 0, false},
+		{"你好", 0, false},
+	}
+
+	for _, tc := range testCases {
+		result, found := tree.Get([]rune(tc.input))
+		if found != tc.found {
+			t.Errorf("Get(%q) found = %v, expected %v", tc.input, found, tc.found)
+		} else if found && (result == nil || *result != tc.expected) {
+			t.Errorf("Get(%q) = %v, expected %d", tc.input, result, tc.expected)
+		}
+	}
+
+	old, ok := tree.Delete([]rune("help"))
+	if !ok || *old != 2 {
+		t.Errorf("Delete(help) = %v, %v, expected 2, true", old, ok)
+	}
+	if _, ok := tree.Get([]rune("help")); ok {
+		t.Error("Get(help) should fail after delete")
+	}
+	if _, ok := tree.Delete([]rune("hel")); ok {
+		t.Error("Delete(hel) should not delete an intermediate node")
+	}
+
+	// Delete all keys concurrently
+	var wg sync.WaitGroup
+	for _, key := range []string{"hello", "helper"} {
+		wg.Add(1)
+		go func(k string) {
+			defer wg.Done()
+			tree.Delete([]rune(k))
+		}(key)
+	}
+	wg.Wait()
+	if tree.Root.NumChildren() != 0 {
+		t.Errorf("Expected 0 children after deleting all keys, got %d", tree.Root.NumChildren())
+	}
+}
+
+func TestConcurrentTreeInsertLeafIsEnd(t *testing.T) {
+	// a leaf added below a node without a matching child stores its key like any other
+	tree := NewConcurrentTree[byte, int]()
+	node := tree.Insert([]byte("hello"), 1)
+	if !node.End {
+		t.Error("Insert into an empty tree returned a node that is not End")
+	}
+	matches := tree.MultiLongestCommonPrefixMatch([]byte("hello"))
+	if last := matches[len(matches)-1]; last.ID != node.ID || !last.Exact {
+		t.Errorf("MultiLongestCommonPrefixMatch(hello) ended with %+v, expected an exact match of node %d", last, node.ID)
+	}
+	if _, _, _, exact := tree.LongestCommonPrefixMatch([]byte("hello")); !exact {
+		t.Error("LongestCommonPrefixMatch(hello) is not exact")
+	}
+	if val, ok := tree.Get([]byte("hello")); !ok || *val != 1 {
+		t.Errorf("Get(hello) = %v, %v, expected 1, true", val, ok)
+	}
+}
+
+func TestConcurrentTreeDeleteOnce(t *testing.T) {
+	tree := NewConcurrentTree[byte, int]()
+	tree.Insert([]byte("hello"), 0)
+	for round := 1; round <= 200; round++ {
+		tree.Insert([]byte("help"), round)
+		var deleted atomic.Int32
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if old, ok := tree.Delete([]byte("help")); ok {
+					deleted.Add(1)
+					if *old != round {
+						t.Errorf("Delete(help) = %d, expected %d", *old, round)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if n := deleted.Load(); n != 1 {
+			t.Fatalf("Delete(help) succeeded %d times in round %d, expected once", n, round)
+		}
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentTreeWalkPrefix(t *testing.T) {
+	tree := NewConcurrentTree[rune, int]()
+	tree.Insert([]rune("hello"), 1)
+	tree.Insert([]rune("help"), 2)
+	tree.Insert([]rune("helper"), 3)
+	tree.Insert([]rune("你好"), 4)
+
+	testCases := []struct {
+		prefix   string
+		expected int
+	}{
+		{"", 4},
+		{"he", 3},
+		{"help", 2},
+		{"helpe", 1},
+		{"hex", 0},
+		{"你", 1},
+	}
+
+	for _, tc := range testCases {
+		keys := tree.KeysWithPrefix([]rune(tc.prefix))
+		if len(keys) != tc.expected {
+			t.Errorf("KeysWithPrefix(%q) = %d keys, expected %d", tc.prefix, len(keys), tc.expected)
+		}
+		for _, key := range keys {
+			if !strings.HasPrefix(string(key), tc.prefix) {
+				t.Errorf("KeysWithPrefix(%q) returned %q", tc.prefix, string(key))
+			}
+		}
+	}
+
+	// fn may modify the tree while walking
+	tree.WalkPrefix([]rune("hel"), func(key []rune, val *int) bool {
+		tree.Delete(key)
+		return true
+	})
+	if keys := tree.KeysWithPrefix([]rune("hel")); len(keys) != 0 {
+		t.Errorf("Expected all hel keys deleted, got %d", len(keys))
+	}
+}
+
+func TestConcurrentTreeWalkPath(t *testing.T) {
+	tree := NewConcurrentTree[rune, int]()
+	tree.Insert([]rune("he"), 2)
+	tree.Insert([]rune("hello"), 5)
+	tree.Insert([]rune("helloworld"), 10)
+	tree.Insert([]rune("help"), 4)
+
+	testCases := []struct {
+		input    string
+		expected []int // lengths of the reported prefixes
+	}{
+		{"helloworld!", []int{2, 5, 10}},
+		{"hello", []int{2, 5}},
+		{"help!", []int{2, 4}},
+		{"hel", []int{2}},
+		{"h", []int{}},
+	}
+
+	for _, tc := range testCases {
+		matches := tree.AllPrefixMatches([]rune(tc.input))
+		if len(matches) != len(tc.expected) {
+			t.Errorf("AllPrefixMatches(%q) = %v, expected %v", tc.input, printMatches(matches), tc.expected)
+			continue
+		}
+		for i, match := range matches {
+			if match.MatchLength != tc.expected[i] {
+				t.Errorf("AllPrefixMatches(%q)[%d] length = %d, expected %d", tc.input, i, match.MatchLength, tc.expected[i])
+			}
+			if match.ID == 0 {
+				t.Errorf("AllPrefixMatches(%q)[%d] has no node ID", tc.input, i)
+			}
+		}
+	}
+
+	keys := []string{}
+	tree.WalkPath([]rune("helloworld"), func(key []rune, val *int) bool {
+		keys = append(keys, string(key))
+		return true
+	})
+	if strings.Join(keys, ",") != "he,hello,helloworld" {
+		t.Errorf("WalkPath(helloworld) keys = %v", keys)
+	}
+}
+
+func TestConcurrentTreeSnapshot(t *testing.T) {
+	tree := NewConcurrentTree[rune, int]()
+	tree.Insert([]rune("hello"), 1)
+	tree.Insert([]rune("help"), 2)
+
+	snapshot := tree.Snapshot()
+	if snapshot.String() != tree.String() {
+		t.Errorf("Snapshot() =\n%s\nexpected\n%s", snapshot.String(), tree.String())
+	}
+	tree.Insert([]rune("world"), 3)
+	if _, ok := snapshot.Get([]rune("world")); ok {
+		t.Error("Insert after snapshot leaked into the snapshot")
+	}
+	if result, ok := snapshot.Get([]rune("help")); !ok || *result != 2 {
+		t.Errorf("Snapshot Get(help) = %v, %v, expected 2, true", result, ok)
+	}
+
+	// Snapshots taken under concurrent writes always contain whole keys
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			key := []rune(fmt.Sprintf("key-%d", i%50))
+			tree.Insert(key, i)
+			if i%3 == 0 {
+				tree.Delete(key)
+			}
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		snapshot := tree.Snapshot()
+		snapshot.Walk(func(key []rune, val *int) bool {
+			if !strings.HasPrefix(string(key), "key-") && !strings.HasPrefix(string(key), "hel") && string(key) != "world" {
+				t.Errorf("Snapshot contains torn key %q", string(key))
+			}
+			return true
+		})
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestConcurrentTreeLongestCommonPrefixLength(t *testing.T) {
+	tree := NewConcurrentTree[byte, int]()
+	for i, key := range []string{"hello", "help", "world", "hi"} {
+		tree.Insert([]byte(key), i)
+	}
+	for _, query := range []string{"", "hello", "hello world", "hel", "helium", "hi", "x"} {
+		id, length, val, exact := tree.LongestCommonPrefixLength([]byte(query))
+		matchID, prefix, matchVal, matchExact := tree.LongestCommonPrefixMatch([]byte(query))
+		if id != matchID || string(prefix) != query[:length] || val != matchVal || exact != matchExact {
+			t.Errorf("LongestCommonPrefixLength(%q) = %d, %d, %v, %v, LongestCommonPrefixMatch = %d, %q, %v, %v",
+				query, id, length, val, exact, matchID, prefix, matchVal, matchExact)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tree.LongestCommonPrefixLength([]byte("hello world"))
+	})
+	if allocs != 0 {
+		t.Errorf("LongestCommonPrefixLength allocated %.1f times per run, expected 0", allocs)
+	}
+}
+
+func TestConcurrentTreeLongestMatchFunc(t *testing.T) {
+	type worker struct {
+		name    string
+		healthy bool
+	}
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tree := NewConcurrentTree[byte, worker](WithClock(clock.Now))
+	short := tree.Insert([]byte("sys"), worker{"w1", true})
+	tree.Insert([]byte("sys prompt"), worker{"w2", false})
+	long := tree.InsertWithTTL([]byte("sys prompt a"), worker{"w3", true}, time.Minute)
+	healthy := func(w *worker) bool {
+		return w.healthy
+	}
+
+	id, length, val, exact := tree.LongestMatchFunc([]byte("sys prompt a"), healthy)
+	if id != long.ID || length != 12 || val.name != "w3" || !exact {
+		t.Errorf("LongestMatchFunc(sys prompt a) = %d, %d, %v, %v, expected w3 exactly", id, length, val, exact)
+	}
+	// the unhealthy w2 is skipped in favour of the shorter prefix held by w1
+	id, length, val, exact = tree.LongestMatchFunc([]byte("sys prompts"), healthy)
+	if id != short.ID || length != 3 || val.name != "w1" || exact {
+		t.Errorf("LongestMatchFunc(sys prompts) = %d, %d, %v, %v, expected w1 with length 3", id, length, val, exact)
+	}
+	// expired values are skipped as well
+	clock.Advance(2 * time.Minute)
+	if _, length, val, _ = tree.LongestMatchFunc([]byte("sys prompt a"), healthy); length != 3 || val.name != "w1" {
+		t.Errorf("LongestMatchFunc(sys prompt a) = %d, %v after expiry, expected w1 with length 3", length, val)
+	}
+	id, length, val, exact = tree.LongestMatchFunc([]byte("other"), healthy)
+	if id != tree.Root.ID || length != 0 || val != nil || exact {
+		t.Errorf("LongestMatchFunc(other) = %d, %d, %v, %v, expected the root without a value", id, length, val, exact)
+	}
+}
+
+func BenchmarkConcurrentTreeLongestCommonPrefixLength(b *testing.B) {
+	keys := benchmarkKeys[byte](50000, 256)
+	tree := NewConcurrentTree[byte, int]()
+	for i, key := range keys {
+		tree.Insert(key, i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			tree.LongestCommonPrefixLength(keys[i%len(keys)])
+			i++
+		}
+	})
+	b.StopTimer()
+	if allocs := testing.AllocsPerRun(100, func() { tree.LongestCommonPrefixLength(keys[0]) }); allocs != 0 {
+		b.Fatalf("LongestCommonPrefixLength allocated %.1f times per run, expected 0", allocs)
+	}
+}
+
+func TestConcurrentTreeInsertCopiesKey(t *testing.T) {
+	tree := NewConcurrentTree[int, int]()
+	buf := make([]int, 4)
+	var wg sync.WaitGroup
+	// a single goroutine reuses one buffer for every key, as with pooled token slices
+	for i := 0; i < 100; i++ {
+		for j := range buf {
+			buf[j] = i*10 + j
+		}
+		tree.Insert(buf, i)
+	}
+	for j := range buf {
+		buf[j] = -1
+	}
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				key := []int{i * 10, i*10 + 1, i*10 + 2, i*10 + 3}
+				if val, ok := tree.Get(key); !ok || *val != i {
+					t.Errorf("Get(%v) = %v, %v after reusing the key buffer, expected %d", key, val, ok, i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if _, ok := tree.Get([]int{-1, -1, -1, -1}); ok {
+		t.Error("Get found the mutated buffer contents")
+	}
+
+	zeroCopy := NewConcurrentTree[int, int](WithZeroCopy())
+	key := []int{1, 2, 3}
+	if node := zeroCopy.Insert(key, 1); &node.Text[0] != &key[0] {
+		t.Error("Expected WithZeroCopy to store the caller's key slice")
+	}
+}
+
+func TestConcurrentTreeValuePolicy(t *testing.T) {
+	for _, policy := range []ValuePolicy{ValueMostRecent, ValueFirstInserted, ValueNone} {
+		t.Run(policy.String(), func(t *testing.T) {
+			tree := NewConcurrentTree[byte, int](WithValuePolicy(policy))
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(seed int64) {
+					defer wg.Done()
+					r := rand.New(rand.NewSource(seed))
+					for i := 0; i < 500; i++ {
+						key := []byte(randomKey(r))
+						if r.Intn(3) == 0 {
+							tree.Delete(key)
+						} else {
+							tree.Insert(key, i)
+						}
+					}
+				}(int64(g))
+			}
+			wg.Wait()
+			snapshot := tree.Snapshot()
+			checkIntermediateValues(t, snapshot.Root, policy)
+			if t.Failed() {
+				t.Log(snapshot.String())
+			}
+		})
+	}
+
+	// the reducer sees the values of every child
+	tree := NewConcurrentTree[byte, int](WithValueReducer(func(children []*int) *int {
+		n := len(children)
+		return &n
+	}))
+	for _, key := range []string{"hello", "help", "helium", "hex"} {
+		tree.Insert([]byte(key), 0)
+	}
+	for _, tc := range []struct {
+		query    string
+		expected int
+	}{
+		{"he", 2},
+		{"hel", 3},
+	} {
+		if _, _, val, _ := tree.LongestCommonPrefixMatch([]byte(tc.query)); val == nil || *val != tc.expected {
+			t.Errorf("LongestCommonPrefixMatch(%q) value = %s, expected %d", tc.query, displayValue(val), tc.expected)
+		}
+	}
+}
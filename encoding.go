@@ -0,0 +1,617 @@
+package lradix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Binary layout of an encoded tree:
+//
+//	magic    [4]byte "LRDX"
+//	version  uint8
+//	flags    uint8   (flagNodeIDs when node IDs are stored)
+//	length   uint64  big-endian length of the body
+//	checksum uint32  big-endian CRC-32 (IEEE) of the body
+//	body     nodes in pre-order, children sorted by first character
+//
+// Every node is written as: [id varint], text length uvarint, text characters,
+// node flags uint8 (nodeEnd, nodeHasVal, nodeExpires, nodeHasSeq), [value], [expiry time varint],
+// [insertion sequence number uvarint], child count uvarint. Versions 1 and 2, without expiry times
+// and without sequence numbers respectively, are still decoded.
+const (
+	encodingMagic   = "LRDX"
+	encodingVersion = 3
+	headerSize      = 4 + 1 + 1 + 8 + 4
+
+	flagNodeIDs = 1 << 0
+
+	nodeEnd     = 1 << 0
+	nodeHasVal  = 1 << 1
+	nodeExpires = 1 << 2
+	nodeHasSeq  = 1 << 3
+)
+
+var (
+	// ErrInvalidMagic is returned when the data does not start with the tree encoding magic.
+	ErrInvalidMagic = errors.New("lradix: invalid magic")
+	// ErrUnsupportedVersion is returned when the data was written by an unknown encoding version.
+	ErrUnsupportedVersion = errors.New("lradix: unsupported encoding version")
+	// ErrChecksumMismatch is returned when the body does not match the checksum in the header.
+	ErrChecksumMismatch = errors.New("lradix: checksum mismatch")
+	// ErrTruncated is returned when the data ends before the encoded tree is complete.
+	ErrTruncated = errors.New("lradix: truncated data")
+	// ErrInvalidNode is returned when a decoded node breaks the tree structure.
+	ErrInvalidNode = errors.New("lradix: invalid node")
+)
+
+// CorruptDataError reports encoded tree data that cannot be decoded.
+// It wraps one of the sentinel errors above or the error returned by a codec.
+type CorruptDataError struct {
+	Offset int   // byte offset in the body, or in the header for header errors
+	Err    error // underlying cause
+}
+
+func (e *CorruptDataError) Error() string {
+	return fmt.Sprintf("lradix: corrupt data at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *CorruptDataError) Unwrap() error {
+	return e.Err
+}
+
+// Codec encodes and decodes single values of type V for tree serialization.
+type Codec[V any] interface {
+	// AppendBinary appends the encoding of v to buf and returns the extended buffer.
+	AppendBinary(buf []byte, v V) ([]byte, error)
+	// DecodeBinary decodes one value from the start of data and returns it with the number of bytes consumed.
+	DecodeBinary(data []byte) (V, int, error)
+}
+
+// Integer is the set of integer kinds supported by IntegerCodec, including byte and rune.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// IntegerCodec encodes integers as varints, zig-zag encoded for signed kinds.
+type IntegerCodec[V Integer] struct{}
+
+func (IntegerCodec[V]) AppendBinary(buf []byte, v V) ([]byte, error) {
+	if signed[V]() {
+		return binary.AppendVarint(buf, int64(v)), nil
+	}
+	return binary.AppendUvarint(buf, uint64(v)), nil
+}
+
+func (IntegerCodec[V]) DecodeBinary(data []byte) (V, int, error) {
+	if signed[V]() {
+		v, n := binary.Varint(data)
+		if n <= 0 {
+			return 0, 0, ErrTruncated
+		}
+		return V(v), n, nil
+	}
+	v, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, 0, ErrTruncated
+	}
+	return V(v), n, nil
+}
+
+// signed reports whether V is a signed integer kind.
+func signed[V Integer]() bool {
+	var zero V
+	return zero-1 < zero
+}
+
+// GobCodec encodes arbitrary values with encoding/gob, each prefixed by its length.
+// It is the fallback used by DefaultCodec for types without a built-in codec.
+type GobCodec[V any] struct{}
+
+func (GobCodec[V]) AppendBinary(buf []byte, v V) ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(&v); err != nil {
+		return buf, err
+	}
+	buf = binary.AppendUvarint(buf, uint64(b.Len()))
+	return append(buf, b.Bytes()...), nil
+}
+
+func (GobCodec[V]) DecodeBinary(data []byte) (V, int, error) {
+	var v V
+	size, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < size {
+		return v, 0, ErrTruncated
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data[n : n+int(size)])).Decode(&v); err != nil {
+		return v, 0, err
+	}
+	return v, n + int(size), nil
+}
+
+// DefaultCodec returns the built-in codec for V: IntegerCodec for the predeclared
+// integer types (including byte and rune) and GobCodec for everything else.
+func DefaultCodec[V any]() Codec[V] {
+	var codec any
+	switch any(*new(V)).(type) {
+	case int:
+		codec = IntegerCodec[int]{}
+	case int8:
+		codec = IntegerCodec[int8]{}
+	case int16:
+		codec = IntegerCodec[int16]{}
+	case int32:
+		codec = IntegerCodec[int32]{}
+	case int64:
+		codec = IntegerCodec[int64]{}
+	case uint:
+		codec = IntegerCodec[uint]{}
+	case uint8:
+		codec = IntegerCodec[uint8]{}
+	case uint16:
+		codec = IntegerCodec[uint16]{}
+	case uint32:
+		codec = IntegerCodec[uint32]{}
+	case uint64:
+		codec = IntegerCodec[uint64]{}
+	case uintptr:
+		codec = IntegerCodec[uintptr]{}
+	default:
+		codec = GobCodec[V]{}
+	}
+	return codec.(Codec[V])
+}
+
+// Encode writes the tree to w, preserving the compressed node layout, End flags, values and
+// insertion order, so that value policies keep ordering the decoded values.
+// Nil codecs are replaced by DefaultCodec.
+func (t *Tree[K, T]) Encode(w io.Writer, kc Codec[K], vc Codec[T]) error {
+	enc := newTreeEncoder(kc, vc)
+	if err := encodeNode(enc, t.Root); err != nil {
+		return err
+	}
+	return enc.writeTo(w, 0)
+}
+
+// DecodeTree reads a tree written by Tree.Encode or ConcurrentTree.Encode from r.
+// Node IDs and expiry times, if present, are ignored. Corrupt data is reported as a *CorruptDataError.
+// Nil codecs are replaced by DefaultCodec.
+func DecodeTree[K comparable, T any](r io.Reader, kc Codec[K], vc Codec[T]) (*Tree[K, T], error) {
+	dec, err := newTreeDecoder(r, kc, vc)
+	if err != nil {
+		return nil, err
+	}
+	root, err := decodeNode(dec, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+	return &Tree[K, T]{Root: root, size: dec.keys, seq: dec.maxSeq}, nil
+}
+
+// MarshalBinary encodes the tree using the default codecs.
+func (t *Tree[K, T]) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	if err := t.Encode(&b, nil, nil); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the contents of the tree with data encoded by MarshalBinary.
+// The tree keeps its options, and subtree summaries are recomputed with its Aggregator.
+func (t *Tree[K, T]) UnmarshalBinary(data []byte) error {
+	decoded, err := DecodeTree[K, T](bytes.NewReader(data), nil, nil)
+	if err != nil {
+		return err
+	}
+	t.summarizeSubtree(decoded.Root)
+	t.Root = decoded.Root
+	t.size = decoded.size
+	t.seq = decoded.seq
+	return nil
+}
+
+// Encode writes the tree to w, preserving the compressed node layout, End flags, values, expiry
+// times, insertion order and node IDs. Expiry times are absolute, so values that expire before
+// the data is decoded are hidden right after decoding, as if they had stayed in the tree.
+// Writers are paused while the tree is encoded, as in Snapshot, so the output is consistent.
+// Nil codecs are replaced by DefaultCodec.
+func (t *ConcurrentTree[K, T]) Encode(w io.Writer, kc Codec[K], vc Codec[T]) error {
+	enc := newTreeEncoder(kc, vc)
+	t.mu.Lock()
+	err := encodeConcurrentNode(enc, t.Root)
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return enc.writeTo(w, flagNodeIDs)
+}
+
+// DecodeConcurrentTree reads a tree written by ConcurrentTree.Encode or Tree.Encode from r.
+// Stored node IDs are restored and the global ID counter is advanced past them;
+// data without IDs gets freshly numbered nodes. Expiry times are restored as well. Corrupt data is reported as a *CorruptDataError.
+// Nil codecs are replaced by DefaultCodec.
+func DecodeConcurrentTree[K comparable, T any](r io.Reader, kc Codec[K], vc Codec[T]) (*ConcurrentTree[K, T], error) {
+	dec, err := newTreeDecoder(r, kc, vc)
+	if err != nil {
+		return nil, err
+	}
+	root, err := decodeConcurrentNode(dec, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.finish(); err != nil {
+		return nil, err
+	}
+	for {
+		cur := nodeNumber.Load()
+		if cur >= dec.maxID || nodeNumber.CompareAndSwap(cur, dec.maxID) {
+			break
+		}
+	}
+	tree := &ConcurrentTree[K, T]{Root: root}
+	tree.size.Store(int64(dec.keys))
+	tree.elements.Store(countElements(root))
+	tree.expiring.Store(dec.ttls)
+	tree.seq.Store(dec.maxSeq)
+	return tree, nil
+}
+
+// MarshalBinary encodes the tree using the default codecs.
+func (t *ConcurrentTree[K, T]) MarshalBinary() ([]byte, error) {
+	var b bytes.Buffer
+	if err := t.Encode(&b, nil, nil); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the contents of the tree with data encoded by MarshalBinary.
+// The tree keeps its options, and subtree summaries are recomputed with its Aggregator.
+// It must not be called while the tree is used by other goroutines.
+func (t *ConcurrentTree[K, T]) UnmarshalBinary(data []byte) error {
+	decoded, err := DecodeConcurrentTree[K, T](bytes.NewReader(data), nil, nil)
+	if err != nil {
+		return err
+	}
+	t.summarizeSubtree(decoded.Root)
+	t.resetLRU(decoded.Root)
+	t.Root = decoded.Root
+	t.size.Store(decoded.size.Load())
+	t.elements.Store(decoded.elements.Load())
+	t.expiring.Store(decoded.expiring.Load())
+	t.seq.Store(decoded.seq.Load())
+	return nil
+}
+
+// treeEncoder accumulates the body of an encoded tree.
+type treeEncoder[K comparable, T any] struct {
+	kc   Codec[K]
+	vc   Codec[T]
+	body []byte
+}
+
+func newTreeEncoder[K comparable, T any](kc Codec[K], vc Codec[T]) *treeEncoder[K, T] {
+	if kc == nil {
+		kc = DefaultCodec[K]()
+	}
+	if vc == nil {
+		vc = DefaultCodec[T]()
+	}
+	return &treeEncoder[K, T]{kc: kc, vc: vc}
+}
+
+// appendNode appends the fields of a single node. Children follow it in the body.
+// An expiry time of zero is not written.
+func (enc *treeEncoder[K, T]) appendNode(id int64, withID bool, text []K, end bool, val *T, seq uint64, expires int64, children int) error {
+	var err error
+	if withID {
+		enc.body = binary.AppendVarint(enc.body, id)
+	}
+	enc.body = binary.AppendUvarint(enc.body, uint64(len(text)))
+	for _, char := range text {
+		if enc.body, err = enc.kc.AppendBinary(enc.body, char); err != nil {
+			return err
+		}
+	}
+	var flags byte
+	if end {
+		flags |= nodeEnd
+	}
+	if val != nil {
+		flags |= nodeHasVal
+	}
+	if expires != 0 {
+		flags |= nodeExpires
+	}
+	if seq != 0 {
+		flags |= nodeHasSeq
+	}
+	enc.body = append(enc.body, flags)
+	if val != nil {
+		if enc.body, err = enc.vc.AppendBinary(enc.body, *val); err != nil {
+			return err
+		}
+	}
+	if expires != 0 {
+		enc.body = binary.AppendVarint(enc.body, expires)
+	}
+	if seq != 0 {
+		enc.body = binary.AppendUvarint(enc.body, seq)
+	}
+	enc.body = binary.AppendUvarint(enc.body, uint64(children))
+	return nil
+}
+
+// writeTo writes the header followed by the body to w.
+func (enc *treeEncoder[K, T]) writeTo(w io.Writer, flags byte) error {
+	header := make([]byte, 0, headerSize)
+	header = append(header, encodingMagic...)
+	header = append(header, encodingVersion, flags)
+	header = binary.BigEndian.AppendUint64(header, uint64(len(enc.body)))
+	header = binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(enc.body))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(enc.body)
+	return err
+}
+
+func encodeNode[K comparable, T any](enc *treeEncoder[K, T], node *Node[K, T]) error {
+	if err := enc.appendNode(0, false, node.Text, node.End, node.Val, node.seq, 0, node.children.len()); err != nil {
+		return err
+	}
+	var err error
+	eachChild(&node.children, compareStable[K], func(child *Node[K, T]) bool {
+		err = encodeNode(enc, child)
+		return err == nil
+	})
+	return err
+}
+
+// encodeConcurrentNode encodes node and its children. The caller must hold t.mu.Lock.
+func encodeConcurrentNode[K comparable, T any](enc *treeEncoder[K, T], node *ConcurrentNode[K, T]) error {
+	if err := enc.appendNode(node.ID, true, node.Text, node.End, node.Val, node.seq, node.expires, node.children.len()); err != nil {
+		return err
+	}
+	var err error
+	eachChild(&node.children, compareStable[K], func(child *ConcurrentNode[K, T]) bool {
+		err = encodeConcurrentNode(enc, child)
+		return err == nil
+	})
+	return err
+}
+
+// treeDecoder reads the body of an encoded tree after the header has been verified.
+type treeDecoder[K comparable, T any] struct {
+	kc      Codec[K]
+	vc      Codec[T]
+	version byte
+	withID  bool
+	body    []byte
+	offset  int
+	maxID   int64
+	maxSeq  uint64
+	keys    int  // number of End nodes decoded
+	ttls    bool // whether any node has an expiry time
+}
+
+// decodedNode holds the fields of a single decoded node.
+type decodedNode[K comparable, T any] struct {
+	id       int64
+	text     []K
+	end      bool
+	val      *T
+	seq      uint64
+	expires  int64
+	children int
+}
+
+func newTreeDecoder[K comparable, T any](r io.Reader, kc Codec[K], vc Codec[T]) (*treeDecoder[K, T], error) {
+	if kc == nil {
+		kc = DefaultCodec[K]()
+	}
+	if vc == nil {
+		vc = DefaultCodec[T]()
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < headerSize {
+		return nil, &CorruptDataError{Offset: len(data), Err: ErrTruncated}
+	}
+	if string(data[:4]) != encodingMagic {
+		return nil, &CorruptDataError{Offset: 0, Err: ErrInvalidMagic}
+	}
+	if data[4] < 1 || data[4] > encodingVersion {
+		return nil, &CorruptDataError{Offset: 4, Err: ErrUnsupportedVersion}
+	}
+	length := binary.BigEndian.Uint64(data[6:14])
+	body := data[headerSize:]
+	if uint64(len(body)) != length {
+		return nil, &CorruptDataError{Offset: 6, Err: ErrTruncated}
+	}
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(data[14:18]) {
+		return nil, &CorruptDataError{Offset: 14, Err: ErrChecksumMismatch}
+	}
+	return &treeDecoder[K, T]{
+		kc:      kc,
+		vc:      vc,
+		version: data[4],
+		withID:  data[5]&flagNodeIDs != 0,
+		body:    body,
+	}, nil
+}
+
+func (dec *treeDecoder[K, T]) corrupt(err error) error {
+	return &CorruptDataError{Offset: dec.offset, Err: err}
+}
+
+func (dec *treeDecoder[K, T]) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(dec.body[dec.offset:])
+	if n <= 0 {
+		return 0, dec.corrupt(ErrTruncated)
+	}
+	dec.offset += n
+	return v, nil
+}
+
+// readNode decodes the fields of the next node in the body.
+func (dec *treeDecoder[K, T]) readNode() (decodedNode[K, T], error) {
+	var node decodedNode[K, T]
+	if dec.withID {
+		id, n := binary.Varint(dec.body[dec.offset:])
+		if n <= 0 {
+			return node, dec.corrupt(ErrTruncated)
+		}
+		dec.offset += n
+		node.id = id
+		dec.maxID = max(dec.maxID, id)
+	}
+	length, err := dec.uvarint()
+	if err != nil {
+		return node, err
+	}
+	if length > uint64(len(dec.body)-dec.offset) {
+		// every character takes at least one byte
+		return node, dec.corrupt(ErrTruncated)
+	}
+	node.text = make([]K, length)
+	for i := range node.text {
+		char, n, err := dec.kc.DecodeBinary(dec.body[dec.offset:])
+		if err != nil {
+			return node, dec.corrupt(err)
+		}
+		node.text[i] = char
+		dec.offset += n
+	}
+	if dec.offset >= len(dec.body) {
+		return node, dec.corrupt(ErrTruncated)
+	}
+	flags := dec.body[dec.offset]
+	dec.offset++
+	node.end = flags&nodeEnd != 0
+	if node.end {
+		dec.keys++
+	}
+	if flags&nodeHasVal != 0 {
+		val, n, err := dec.vc.DecodeBinary(dec.body[dec.offset:])
+		if err != nil {
+			return node, dec.corrupt(err)
+		}
+		node.val = &val
+		dec.offset += n
+	}
+	if flags&nodeExpires != 0 {
+		if dec.version < 2 {
+			return node, dec.corrupt(ErrInvalidNode)
+		}
+		expires, n := binary.Varint(dec.body[dec.offset:])
+		if n <= 0 {
+			return node, dec.corrupt(ErrTruncated)
+		}
+		dec.offset += n
+		node.expires = expires
+		dec.ttls = true
+	}
+	if flags&nodeHasSeq != 0 {
+		if dec.version < 3 {
+			return node, dec.corrupt(ErrInvalidNode)
+		}
+		seq, err := dec.uvarint()
+		if err != nil {
+			return node, err
+		}
+		node.seq = seq
+		dec.maxSeq = max(dec.maxSeq, seq)
+	}
+	children, err := dec.uvarint()
+	if err != nil {
+		return node, err
+	}
+	if children > uint64(len(dec.body)-dec.offset) {
+		// every child takes at least one byte
+		return node, dec.corrupt(ErrTruncated)
+	}
+	node.children = int(children)
+	return node, nil
+}
+
+// finish checks that the whole body was consumed.
+func (dec *treeDecoder[K, T]) finish() error {
+	if dec.offset != len(dec.body) {
+		return dec.corrupt(ErrInvalidNode)
+	}
+	return nil
+}
+
+func decodeNode[K comparable, T any](dec *treeDecoder[K, T], isRoot bool) (*Node[K, T], error) {
+	fields, err := dec.readNode()
+	if err != nil {
+		return nil, err
+	}
+	if isRoot != (len(fields.text) == 0) {
+		// only the root has an empty text
+		return nil, dec.corrupt(ErrInvalidNode)
+	}
+	node := &Node[K, T]{
+		Text: fields.text,
+		Val:  fields.val,
+		End:  fields.end,
+		seq:  fields.seq,
+	}
+	for i := 0; i < fields.children; i++ {
+		child, err := decodeNode(dec, false)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := node.GetChild(child.Text[0]); ok {
+			return nil, dec.corrupt(ErrInvalidNode)
+		}
+		node.AddChild(child)
+	}
+	return node, nil
+}
+
+func decodeConcurrentNode[K comparable, T any](dec *treeDecoder[K, T], isRoot bool) (*ConcurrentNode[K, T], error) {
+	fields, err := dec.readNode()
+	if err != nil {
+		return nil, err
+	}
+	if isRoot != (len(fields.text) == 0) {
+		// only the root has an empty text
+		return nil, dec.corrupt(ErrInvalidNode)
+	}
+	var node *ConcurrentNode[K, T]
+	if dec.withID {
+		node = &ConcurrentNode[K, T]{
+			ID:   fields.id,
+			Text: fields.text,
+			Val:  fields.val,
+			End:  fields.end,
+		}
+	} else {
+		node = NewConcurrentNode(fields.text, fields.val, fields.end)
+	}
+	node.seq, node.expires = fields.seq, fields.expires
+	for i := 0; i < fields.children; i++ {
+		child, err := decodeConcurrentNode(dec, false)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := node.GetChild(child.Text[0]); ok {
+			return nil, dec.corrupt(ErrInvalidNode)
+		}
+		node.AddChild(child)
+	}
+	return node, nil
+}
@@ -0,0 +1,259 @@
+package lradix
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTreeEncodeDecode(t *testing.T) {
+	tree := NewTree[byte, int]()
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), -2)
+	tree.Insert([]byte("helper"), 300)
+	tree.Insert([]byte("world"), 4)
+
+	data, err := tree.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	decoded := NewTree[byte, int]()
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if decoded.String() != tree.String() {
+		t.Errorf("Decoded tree =\n%s\nexpected\n%s", decoded.String(), tree.String())
+	}
+	for _, key := range []string{"hello", "help", "helper", "world", "hel"} {
+		v1, ok1 := tree.Get([]byte(key))
+		v2, ok2 := decoded.Get([]byte(key))
+		if ok1 != ok2 || (ok1 && *v1 != *v2) {
+			t.Errorf("Get(%q) = %v, %v after decode, expected %v, %v", key, v2, ok2, v1, ok1)
+		}
+	}
+
+	// Encoding is deterministic
+	again, _ := decoded.MarshalBinary()
+	if !bytes.Equal(data, again) {
+		t.Error("Encoding the decoded tree produced different bytes")
+	}
+}
+
+func TestEncodeKeepsInsertionOrder(t *testing.T) {
+	for _, policy := range []ValuePolicy{ValueMostRecent, ValueFirstInserted} {
+		tree := NewTree[byte, int](WithValuePolicy(policy))
+		tree.Insert([]byte("ab"), 1)
+		tree.Insert([]byte("ac"), 2)
+		tree.Insert([]byte("ad"), 3)
+		tree.Insert([]byte("ab"), 4)
+		data, err := tree.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error = %v", err)
+		}
+		decoded := NewTree[byte, int](WithValuePolicy(policy))
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary() error = %v", err)
+		}
+		concurrent := NewConcurrentTree[byte, int](WithValuePolicy(policy))
+		if err := concurrent.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary() error = %v", err)
+		}
+
+		// removing ab leaves ac and ad to order, and a later insert is the most recent of all
+		check := func(step string) {
+			t.Helper()
+			_, expected, _ := tree.LongestCommonPrefixLength([]byte("a"))
+			if _, got, _ := decoded.LongestCommonPrefixLength([]byte("a")); *got != *expected {
+				t.Errorf("Value of a = %d with %s after decode and %s, expected %d", *got, policy, step, *expected)
+			}
+			if _, _, got, _ := concurrent.LongestCommonPrefixLength([]byte("a")); *got != *expected {
+				t.Errorf("Value of a = %d with %s after concurrent decode and %s, expected %d", *got, policy, step, *expected)
+			}
+		}
+		tree.Delete([]byte("ab"))
+		decoded.Delete([]byte("ab"))
+		concurrent.Delete([]byte("ab"))
+		check("Delete(ab)")
+		tree.Insert([]byte("ae"), 5)
+		decoded.Insert([]byte("ae"), 5)
+		concurrent.Insert([]byte("ae"), 5)
+		check("Insert(ae)")
+	}
+}
+
+func TestTreeEncodeGobValues(t *testing.T) {
+	type route struct {
+		Backend string
+		Weight  int
+	}
+	tree := NewTree[rune, route]()
+	tree.Insert([]rune("/api"), route{"api", 1})
+	tree.Insert([]rune("/api/v2"), route{"api-v2", 2})
+	tree.Insert([]rune("/静态"), route{"static", 3})
+
+	var b bytes.Buffer
+	if err := tree.Encode(&b, nil, nil); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	decoded, err := DecodeTree[rune, route](&b, nil, nil)
+	if err != nil {
+		t.Fatalf("DecodeTree() error = %v", err)
+	}
+	if result, ok := decoded.Get([]rune("/api/v2")); !ok || result.Backend != "api-v2" {
+		t.Errorf("Get(/api/v2) = %v, %v after decode", result, ok)
+	}
+	if result, ok := decoded.Get([]rune("/静态")); !ok || result.Weight != 3 {
+		t.Errorf("Get(/静态) = %v, %v after decode", result, ok)
+	}
+}
+
+func TestConcurrentTreeEncodeDecode(t *testing.T) {
+	tree := NewConcurrentTree[rune, int]()
+	tree.Insert([]rune("hello"), 1)
+	node := tree.Insert([]rune("help"), 2)
+
+	data, err := tree.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	decoded, err := DecodeConcurrentTree[rune, int](bytes.NewReader(data), nil, nil)
+	if err != nil {
+		t.Fatalf("DecodeConcurrentTree() error = %v", err)
+	}
+	id, _, result, exact := decoded.LongestCommonPrefixMatch([]rune("help"))
+	if id != node.ID || result == nil || *result != 2 || !exact {
+		t.Errorf("LCP(help) = %d, %v, %v after decode, expected %d, 2, true", id, result, exact, node.ID)
+	}
+	// New nodes never reuse restored IDs
+	if fresh := decoded.Insert([]rune("world"), 3); fresh.ID <= node.ID {
+		t.Errorf("New node ID %d collides with restored IDs", fresh.ID)
+	}
+
+	// A plain Tree can be decoded from a concurrent encoding and vice versa
+	plain, err := DecodeTree[rune, int](bytes.NewReader(data), nil, nil)
+	if err != nil {
+		t.Fatalf("DecodeTree() error = %v", err)
+	}
+	if plain.String() != tree.String() {
+		t.Errorf("Decoded tree =\n%s\nexpected\n%s", plain.String(), tree.String())
+	}
+	var b bytes.Buffer
+	if err := plain.Encode(&b, nil, nil); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if _, err := DecodeConcurrentTree[rune, int](&b, nil, nil); err != nil {
+		t.Errorf("DecodeConcurrentTree() of a Tree encoding error = %v", err)
+	}
+}
+
+func TestConcurrentTreeEncodeTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tree := NewConcurrentTree[byte, int](WithClock(clock.Now))
+	tree.Insert([]byte("forever"), 1)
+	tree.InsertWithTTL([]byte("soon"), 2, time.Minute)
+	tree.InsertWithTTL([]byte("later"), 3, time.Hour)
+
+	data, err := tree.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	decoded := NewConcurrentTree[byte, int](WithClock(clock.Now))
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	clock.Advance(2 * time.Minute)
+	if _, ok := decoded.Get([]byte("soon")); ok {
+		t.Error("Get(soon) found a value whose expiry time was lost by encoding")
+	}
+	for _, key := range []string{"forever", "later"} {
+		if _, ok := decoded.Get([]byte(key)); !ok {
+			t.Errorf("Get(%s) found no value after decoding", key)
+		}
+	}
+	if removed := decoded.RemoveExpired(); removed != 1 {
+		t.Errorf("RemoveExpired() = %d after decoding, expected 1", removed)
+	}
+
+	// data written before expiry times were encoded is still decoded
+	data[4] = 1
+	if _, err := DecodeConcurrentTree[byte, int](bytes.NewReader(data), nil, nil); err == nil {
+		t.Error("DecodeConcurrentTree() of version 1 data with expiry times should fail")
+	}
+	plain, err := NewTree[byte, int]().MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	plain[4] = 1
+	if _, err := DecodeConcurrentTree[byte, int](bytes.NewReader(plain), nil, nil); err != nil {
+		t.Errorf("DecodeConcurrentTree() of version 1 data error = %v", err)
+	}
+}
+
+func TestDecodeCorruptData(t *testing.T) {
+	tree := NewTree[byte, int]()
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2)
+	data, _ := tree.MarshalBinary()
+
+	corrupt := func(mutate func(b []byte) []byte) []byte {
+		return mutate(bytes.Clone(data))
+	}
+	testCases := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"empty", []byte{}, ErrTruncated},
+		{"magic", corrupt(func(b []byte) []byte { b[0] = 'X'; return b }), ErrInvalidMagic},
+		{"version", corrupt(func(b []byte) []byte { b[4] = 99; return b }), ErrUnsupportedVersion},
+		{"body", corrupt(func(b []byte) []byte { b[len(b)-3] ^= 0xff; return b }), ErrChecksumMismatch},
+		{"truncated", corrupt(func(b []byte) []byte { return b[:len(b)-1] }), ErrTruncated},
+	}
+
+	for _, tc := range testCases {
+		err := NewTree[byte, int]().UnmarshalBinary(tc.data)
+		if !errors.Is(err, tc.expected) {
+			t.Errorf("%s: UnmarshalBinary() error = %v, expected %v", tc.name, err, tc.expected)
+		}
+		var corruptErr *CorruptDataError
+		if !errors.As(err, &corruptErr) {
+			t.Errorf("%s: UnmarshalBinary() error %T is not a *CorruptDataError", tc.name, err)
+		}
+	}
+
+	// A structurally invalid body with a valid checksum is rejected too
+	enc := newTreeEncoder[byte, int](nil, nil)
+	enc.appendNode(0, false, []byte{}, false, nil, 0, 0, 1)
+	enc.appendNode(0, false, []byte{}, true, nil, 0, 0, 0) // non-root node with empty text
+	var b bytes.Buffer
+	enc.writeTo(&b, 0)
+	if _, err := DecodeTree[byte, int](&b, nil, nil); !errors.Is(err, ErrInvalidNode) {
+		t.Errorf("DecodeTree() of an invalid node error = %v, expected %v", err, ErrInvalidNode)
+	}
+}
+
+func TestIntegerCodec(t *testing.T) {
+	signedCodec := IntegerCodec[int16]{}
+	for _, v := range []int16{0, 1, -1, 127, -128, 32767, -32768} {
+		buf, _ := signedCodec.AppendBinary(nil, v)
+		got, n, err := signedCodec.DecodeBinary(buf)
+		if err != nil || got != v || n != len(buf) {
+			t.Errorf("IntegerCodec[int16] round trip of %d = %d, %d, %v", v, got, n, err)
+		}
+	}
+	unsignedCodec := IntegerCodec[uint64]{}
+	for _, v := range []uint64{0, 1, 1 << 63} {
+		buf, _ := unsignedCodec.AppendBinary(nil, v)
+		got, n, err := unsignedCodec.DecodeBinary(buf)
+		if err != nil || got != v || n != len(buf) {
+			t.Errorf("IntegerCodec[uint64] round trip of %d = %d, %d, %v", v, got, n, err)
+		}
+	}
+	if _, ok := DefaultCodec[rune]().(IntegerCodec[int32]); !ok {
+		t.Error("DefaultCodec[rune] should be IntegerCodec")
+	}
+	if _, ok := DefaultCodec[string]().(GobCodec[string]); !ok {
+		t.Error("DefaultCodec[string] should be GobCodec")
+	}
+}
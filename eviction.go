@@ -0,0 +1,272 @@
+package lradix
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// WithMaxKeys bounds a ConcurrentTree to at most n stored keys. An Insert that exceeds the
+// bound evicts the least recently used leaves until the tree fits again; see WithEvictCallback.
+// A node is used when it is inserted or lies on the path matched by LongestCommonPrefixMatch,
+// LongestCommonPrefixLength or MultiLongestCommonPrefixMatch. Zero means unbounded.
+// Tree ignores this option.
+func WithMaxKeys(n int) Option {
+	return func(o *treeOptions) {
+		o.maxKeys = n
+	}
+}
+
+// WithMaxElements bounds a ConcurrentTree to at most n key elements summed over the text of all
+// nodes, so that shared prefixes are only counted once. Eviction works as for WithMaxKeys, and both
+// bounds may be set together. A key that alone exceeds the bound is evicted by its own Insert.
+// Zero means unbounded. Tree ignores this option.
+func WithMaxElements(n int) Option {
+	return func(o *treeOptions) {
+		o.maxElements = n
+	}
+}
+
+// WithEvictCallback makes a bounded ConcurrentTree call fn with the key and value of every entry
+// it evicts. fn runs after Insert has released the tree's locks, so it may call back into the tree.
+// K and T must match the key and value types of the tree, otherwise NewConcurrentTree panics.
+func WithEvictCallback[K comparable, T any](fn func(key []K, val *T)) Option {
+	return func(o *treeOptions) {
+		o.onEvict = fn
+	}
+}
+
+// evicted is an entry removed by eviction, reported to the eviction callback.
+type evicted[K comparable, T any] struct {
+	key []K
+	val *T
+}
+
+func newEvictCallback[K comparable, T any](o treeOptions) func(key []K, val *T) {
+	if o.onEvict == nil {
+		return nil
+	}
+	fn, ok := o.onEvict.(func(key []K, val *T))
+	if !ok {
+		panic(fmt.Sprintf("lradix: eviction callback %T does not match key type %T and value type %T", o.onEvict, *new(K), *new(T)))
+	}
+	return fn
+}
+
+// bounded reports whether the tree was created with a capacity and tracks access times.
+func (t *ConcurrentTree[K, T]) bounded() bool {
+	return t.opts.maxKeys > 0 || t.opts.maxElements > 0
+}
+
+// overCapacity reports whether the tree holds more keys or text elements than allowed.
+func (t *ConcurrentTree[K, T]) overCapacity() bool {
+	return t.opts.maxKeys > 0 && t.Len() > t.opts.maxKeys ||
+		t.opts.maxElements > 0 && t.elements.Load() > int64(t.opts.maxElements)
+}
+
+// tick returns a new access time, or zero if the tree does not track access times.
+func (t *ConcurrentTree[K, T]) tick() uint64 {
+	if !t.bounded() {
+		return 0
+	}
+	return t.clock.Add(1)
+}
+
+// touch records an access to node at time now, as returned by tick. The access time is atomic,
+// so it may be recorded while holding only a read lock or no lock at all. A leaf waiting in the
+// LRU heap is moved to its new place.
+func (t *ConcurrentTree[K, T]) touch(node *ConcurrentNode[K, T], now uint64) {
+	if now == 0 {
+		return
+	}
+	node.access.Store(now)
+	if node.lruSlot.Load() == 0 {
+		return
+	}
+	t.lruMu.Lock()
+	if slot := node.lruSlot.Load(); slot > 0 {
+		node.lruAccess = node.access.Load()
+		heap.Fix(&t.lru, int(slot-1))
+	}
+	t.lruMu.Unlock()
+}
+
+// lruHeap holds the evictable leaves of a bounded tree, that is the unpinned End nodes without
+// children, least recently used first. It is guarded by the tree's lruMu.
+type lruHeap[K comparable, T any] []*ConcurrentNode[K, T]
+
+func (h lruHeap[K, T]) Len() int           { return len(h) }
+func (h lruHeap[K, T]) Less(i, j int) bool { return h[i].lruAccess < h[j].lruAccess }
+
+func (h lruHeap[K, T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].lruSlot.Store(int64(i + 1))
+	h[j].lruSlot.Store(int64(j + 1))
+}
+
+func (h *lruHeap[K, T]) Push(x any) {
+	node := x.(*ConcurrentNode[K, T])
+	*h = append(*h, node)
+	node.lruSlot.Store(int64(len(*h)))
+}
+
+func (h *lruHeap[K, T]) Pop() any {
+	old := *h
+	node := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	node.lruSlot.Store(0)
+	return node
+}
+
+// trackLocked adds node to the LRU heap if it is an evictable leaf, and removes it otherwise.
+// It is called after every change that can make a node evictable or not, while still holding the
+// node's lock, so that the heap always ends up following the latest state of every node.
+func (t *ConcurrentTree[K, T]) trackLocked(node *ConcurrentNode[K, T]) {
+	if !t.bounded() {
+		return
+	}
+	evictable := node.Parent != nil && node.End && node.children.len() == 0 && node.pins == 0
+	t.lruMu.Lock()
+	defer t.lruMu.Unlock()
+	slot := node.lruSlot.Load()
+	switch {
+	case evictable && slot == 0:
+		// publish the slot before reading the access time, so that a concurrent touch
+		// either sees the slot and fixes the heap, or stored its time before it is read here
+		node.lruSlot.Store(int64(len(t.lru) + 1))
+		node.lruAccess = node.access.Load()
+		heap.Push(&t.lru, node)
+	case !evictable && slot > 0:
+		heap.Remove(&t.lru, int(slot-1))
+	}
+}
+
+// track is trackLocked for callers that do not hold the node's lock.
+func (t *ConcurrentTree[K, T]) track(node *ConcurrentNode[K, T]) {
+	if !t.bounded() {
+		return
+	}
+	node.RLock()
+	t.trackLocked(node)
+	node.RUnlock()
+}
+
+// resetLRU rebuilds the LRU heap from the leaves below root, for trees whose nodes were built
+// without going through Insert. The nodes must not be reachable by other goroutines yet.
+func (t *ConcurrentTree[K, T]) resetLRU(root *ConcurrentNode[K, T]) {
+	t.lruMu.Lock()
+	t.lru = nil
+	t.lruMu.Unlock()
+	var visit func(node *ConcurrentNode[K, T])
+	visit = func(node *ConcurrentNode[K, T]) {
+		t.trackLocked(node)
+		node.children.each(func(_ K, child *ConcurrentNode[K, T]) bool {
+			visit(child)
+			return true
+		})
+	}
+	visit(root)
+}
+
+// evict removes the least recently used leaves until the tree is within its capacity, and returns
+// the removed entries. Victims are popped from the LRU heap, which also receives the parents that
+// become leaves as their children are evicted. Evictions are serialized, but run concurrently
+// with other writers. The caller must hold t.mu.RLock.
+func (t *ConcurrentTree[K, T]) evict() []evicted[K, T] {
+	if !t.bounded() || !t.overCapacity() {
+		return nil
+	}
+	t.evictMu.Lock()
+	defer t.evictMu.Unlock()
+	var removed []evicted[K, T]
+	for t.overCapacity() {
+		node := t.popLRU()
+		if node == nil {
+			// every remaining leaf is pinned
+			return removed
+		}
+		var key []K
+		if t.onEvict != nil {
+			if key = t.keyOf(node); key == nil {
+				// removed concurrently since it was popped
+				continue
+			}
+		}
+		var val *T
+		isLeaf := func(node *ConcurrentNode[K, T]) bool {
+			val = node.Val
+			return node.End && node.children.len() == 0
+		}
+		if ok, _ := t.removeNode(node, isLeaf); !ok {
+			// changed, pinned or removed concurrently since it was popped;
+			// the change puts it back into the heap once it is evictable again
+			continue
+		}
+		removed = append(removed, evicted[K, T]{key, val})
+	}
+	return removed
+}
+
+// popLRU removes the least recently used leaf from the LRU heap and returns it, or nil if the heap is empty.
+func (t *ConcurrentTree[K, T]) popLRU() *ConcurrentNode[K, T] {
+	t.lruMu.Lock()
+	defer t.lruMu.Unlock()
+	if len(t.lru) == 0 {
+		return nil
+	}
+	return heap.Pop(&t.lru).(*ConcurrentNode[K, T])
+}
+
+// keyOf returns the full key of node, or nil if the node is no longer in the tree. Nodes are
+// read-locked one at a time from node up to the root. The full key of a node never changes while
+// it stays in the tree, since splits and merges above it only move text between its ancestors,
+// so the walk only starts over when an ancestor is merged away while it is being read.
+func (t *ConcurrentTree[K, T]) keyOf(node *ConcurrentNode[K, T]) []K {
+	for {
+		var parts [][]K
+		n := 0
+		mark := node
+		for mark != t.Root {
+			mark.RLock()
+			text, parent := mark.Text, mark.Parent
+			mark.RUnlock()
+			if parent == nil {
+				break
+			}
+			parts = append(parts, text)
+			n += len(text)
+			mark = parent
+		}
+		if mark == t.Root {
+			key := make([]K, 0, n)
+			for i := len(parts) - 1; i >= 0; i-- {
+				key = append(key, parts[i]...)
+			}
+			return key
+		}
+		if mark == node {
+			return nil
+		}
+	}
+}
+
+// notifyEvicted reports evicted entries to the eviction callback. The caller must not hold any lock.
+func (t *ConcurrentTree[K, T]) notifyEvicted(removed []evicted[K, T]) {
+	if t.onEvict == nil {
+		return
+	}
+	for _, e := range removed {
+		t.onEvict(e.key, e.val)
+	}
+}
+
+// countElements returns the number of key elements in the text of node and its descendants,
+// for trees built without Insert. The caller must ensure no other goroutine uses the nodes.
+func countElements[K comparable, T any](node *ConcurrentNode[K, T]) int64 {
+	n := int64(len(node.Text))
+	node.children.each(func(_ K, child *ConcurrentNode[K, T]) bool {
+		n += countElements(child)
+		return true
+	})
+	return n
+}
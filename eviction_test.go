@@ -0,0 +1,174 @@
+package lradix
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentTreeMaxKeys(t *testing.T) {
+	var evictedKeys []string
+	var evictedVals []int
+	tree := NewConcurrentTree[byte, int](WithMaxKeys(3), WithEvictCallback(func(key []byte, val *int) {
+		evictedKeys = append(evictedKeys, string(key))
+		evictedVals = append(evictedVals, *val)
+	}))
+	tree.Insert([]byte("apple"), 1)
+	tree.Insert([]byte("banana"), 2)
+	tree.Insert([]byte("cherry"), 3)
+	// use apple and a prefix of banana, leaving cherry as the least recently used
+	tree.LongestCommonPrefixMatch([]byte("apple pie"))
+	tree.MultiLongestCommonPrefixMatch([]byte("ban"))
+	tree.Insert([]byte("date"), 4)
+
+	if !slices.Equal(evictedKeys, []string{"cherry"}) || !slices.Equal(evictedVals, []int{3}) {
+		t.Errorf("Evicted %q with values %v, expected [cherry] with [3]", evictedKeys, evictedVals)
+	}
+	if tree.Len() != 3 {
+		t.Errorf("Len() = %d after eviction, expected 3", tree.Len())
+	}
+	if _, ok := tree.Get([]byte("cherry")); ok {
+		t.Error("Evicted key is still stored")
+	}
+
+	tree.LongestCommonPrefixMatch([]byte("apple"))
+	tree.LongestCommonPrefixMatch([]byte("date"))
+	tree.Insert([]byte("elder"), 5)
+	if !slices.Equal(evictedKeys, []string{"cherry", "banana"}) {
+		t.Errorf("Evicted %q, expected [cherry banana]", evictedKeys)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentTreeMaxElements(t *testing.T) {
+	var evictedKeys []string
+	tree := NewConcurrentTree[byte, int](WithMaxElements(9), WithEvictCallback(func(key []byte, _ *int) {
+		evictedKeys = append(evictedKeys, string(key))
+	}))
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2) // "hel" + "lo" + "p", 6 elements
+	if len(evictedKeys) != 0 {
+		t.Fatalf("Evicted %q within capacity", evictedKeys)
+	}
+	tree.LongestCommonPrefixMatch([]byte("help me"))
+	tree.Insert([]byte("world"), 3) // 11 elements, evicting hello merges "hel" and "p" into "help"
+	if !slices.Equal(evictedKeys, []string{"hello"}) {
+		t.Errorf("Evicted %q, expected [hello]", evictedKeys)
+	}
+	if got := tree.Stats().TextElements; got != 9 {
+		t.Errorf("TextElements = %d, expected 9", got)
+	}
+	tree.LongestCommonPrefixMatch([]byte("world"))
+	tree.Insert([]byte("wo"), 4)
+	tree.Insert([]byte("ab"), 5) // 11 elements again, help is the least recently used
+	if !slices.Equal(evictedKeys, []string{"hello", "help"}) {
+		t.Errorf("Evicted %q, expected [hello help]", evictedKeys)
+	}
+	for _, key := range []string{"world", "wo", "ab"} {
+		if _, ok := tree.Get([]byte(key)); !ok {
+			t.Errorf("Key %q was evicted", key)
+		}
+	}
+
+	// a key larger than the whole budget is evicted by its own insert, after the older leaves
+	tree.Insert([]byte("much too long"), 6)
+	if _, ok := tree.Get([]byte("much too long")); ok {
+		t.Error("Key exceeding the capacity is still stored")
+	}
+	if got := tree.Stats().TextElements; got > 9 {
+		t.Errorf("TextElements = %d, expected at most 9", got)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentTreeEvictParentLeaf(t *testing.T) {
+	var evictedKeys []string
+	tree := NewConcurrentTree[byte, int](WithMaxKeys(2), WithEvictCallback(func(key []byte, _ *int) {
+		evictedKeys = append(evictedKeys, string(key))
+	}))
+	tree.Insert([]byte("a"), 1)
+	tree.Insert([]byte("ab"), 2)
+	tree.Insert([]byte("ac"), 3)
+	tree.Insert([]byte("x"), 4)
+	tree.Insert([]byte("y"), 5)
+	// a is only a leaf once both of its children are gone
+	if !slices.Equal(evictedKeys, []string{"ab", "ac", "a"}) {
+		t.Errorf("Evicted %q, expected [ab ac a]", evictedKeys)
+	}
+}
+
+func TestConcurrentTreeEvictionConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	evictions := 0
+	tree := NewConcurrentTree[byte, int](WithMaxKeys(20), WithMaxElements(40), WithEvictCallback(func(key []byte, _ *int) {
+		mu.Lock()
+		evictions++
+		mu.Unlock()
+	}))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < 500; i++ {
+				key := []byte(randomKey(r))
+				switch r.Intn(4) {
+				case 0:
+					tree.Delete(key)
+				case 1:
+					tree.MultiLongestCommonPrefixMatch(key)
+				case 2:
+					tree.LongestCommonPrefixMatch(key)
+				default:
+					tree.Insert(key, i)
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+
+	if tree.Len() > 20 {
+		t.Errorf("Len() = %d, expected at most 20", tree.Len())
+	}
+	if got := tree.Stats().TextElements; int64(got) != tree.elements.Load() || got > 40 {
+		t.Errorf("TextElements = %d, tracked %d, expected at most 40", got, tree.elements.Load())
+	}
+	if evictions == 0 {
+		t.Error("Expected evictions")
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEvictCallbackMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected NewConcurrentTree with a callback for another value type to panic")
+		}
+	}()
+	NewConcurrentTree[byte, string](WithEvictCallback(func(key []byte, val *int) {}))
+}
+
+func BenchmarkConcurrentTreeInsertEvict(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	keys := make([][]byte, 1<<16)
+	for i := range keys {
+		keys[i] = strconv.AppendUint(nil, r.Uint64(), 36)
+	}
+	tree := NewConcurrentTree[byte, int](WithMaxKeys(len(keys) / 4))
+	for i, key := range keys {
+		tree.Insert(key, i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.Insert(keys[i%len(keys)], i)
+	}
+}
@@ -0,0 +1,246 @@
+package lradix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// JSONFormat selects the layout used by ExportJSON and ImportJSON.
+type JSONFormat int
+
+const (
+	// JSONFlat renders the tree as an array of {"key": ..., "value": ...} entries,
+	// one for every stored key, in stable order.
+	JSONFlat JSONFormat = iota
+	// JSONNested renders the node layout itself: every node has its text, end flag,
+	// value and children, starting from the root.
+	JSONNested
+)
+
+// Keys and node texts of type []byte or []rune are rendered as JSON strings when they are
+// valid UTF-8, any other key or text as a JSON array of its characters, so that binary keys
+// and texts splitting a multi-byte character survive a round trip.
+type jsonEntry[T any] struct {
+	Key   json.RawMessage `json:"key"`
+	Value *T              `json:"value"`
+}
+
+type jsonNode[T any] struct {
+	Text     json.RawMessage `json:"text"`
+	End      bool            `json:"end"`
+	Value    *T              `json:"value"`
+	Children []*jsonNode[T]  `json:"children,omitempty"`
+}
+
+// ExportJSON writes the contents of the tree to w as JSON in the given format.
+func (t *Tree[K, T]) ExportJSON(w io.Writer, format JSONFormat) error {
+	var doc any
+	switch format {
+	case JSONFlat:
+		entries := []jsonEntry[T]{}
+		var err error
+		walkNode(t.Root, []K{}, compareStable[K], func(key []K, val *T) bool {
+			var raw json.RawMessage
+			if raw, err = marshalJSONText(key); err != nil {
+				return false
+			}
+			entries = append(entries, jsonEntry[T]{Key: raw, Value: val})
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		doc = entries
+	case JSONNested:
+		root, err := exportJSONNode(t.Root)
+		if err != nil {
+			return err
+		}
+		doc = root
+	default:
+		return fmt.Errorf("lradix: unknown JSON format %d", format)
+	}
+	return json.NewEncoder(w).Encode(doc)
+}
+
+// ImportJSON builds a tree from JSON written by ExportJSON in the given format.
+// Flat entries are inserted in order; nested documents restore the node layout as is.
+func ImportJSON[K comparable, T any](r io.Reader, format JSONFormat) (*Tree[K, T], error) {
+	switch format {
+	case JSONFlat:
+		var entries []jsonEntry[T]
+		if err := json.NewDecoder(r).Decode(&entries); err != nil {
+			return nil, err
+		}
+		tree := NewTree[K, T]()
+		for _, entry := range entries {
+			key, err := unmarshalJSONText[K](entry.Key)
+			if err != nil {
+				return nil, err
+			}
+			var val T
+			if entry.Value != nil {
+				val = *entry.Value
+			}
+			tree.Insert(key, val)
+		}
+		return tree, nil
+	case JSONNested:
+		var doc jsonNode[T]
+		if err := json.NewDecoder(r).Decode(&doc); err != nil {
+			return nil, err
+		}
+		root, err := importJSONNode[K](&doc, true)
+		if err != nil {
+			return nil, err
+		}
+		return &Tree[K, T]{Root: root, size: countKeys(root)}, nil
+	default:
+		return nil, fmt.Errorf("lradix: unknown JSON format %d", format)
+	}
+}
+
+// ExportJSON writes the contents of the tree to w as JSON in the given format.
+// The output is taken from a consistent Snapshot of the tree.
+func (t *ConcurrentTree[K, T]) ExportJSON(w io.Writer, format JSONFormat) error {
+	return t.Snapshot().ExportJSON(w, format)
+}
+
+// ImportConcurrentJSON builds a concurrent tree from JSON written by ExportJSON in the given format.
+// Nodes get fresh IDs.
+func ImportConcurrentJSON[K comparable, T any](r io.Reader, format JSONFormat) (*ConcurrentTree[K, T], error) {
+	tree, err := ImportJSON[K, T](r, format)
+	if err != nil {
+		return nil, err
+	}
+	ctree := &ConcurrentTree[K, T]{Root: concurrentNodeFrom(tree.Root)}
+	ctree.size.Store(int64(tree.Len()))
+	ctree.elements.Store(countElements(ctree.Root))
+	return ctree, nil
+}
+
+// exportJSONNode recursively converts a node and its children, in stable order, to its JSON form.
+func exportJSONNode[K comparable, T any](node *Node[K, T]) (*jsonNode[T], error) {
+	text, err := marshalJSONText(node.Text)
+	if err != nil {
+		return nil, err
+	}
+	doc := &jsonNode[T]{Text: text, End: node.End, Value: node.Val}
+	eachChild(&node.children, compareStable[K], func(child *Node[K, T]) bool {
+		var childDoc *jsonNode[T]
+		if childDoc, err = exportJSONNode(child); err != nil {
+			return false
+		}
+		doc.Children = append(doc.Children, childDoc)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// importJSONNode recursively rebuilds a node and its children from its JSON form,
+// rejecting layouts that break the tree structure.
+func importJSONNode[K comparable, T any](doc *jsonNode[T], isRoot bool) (*Node[K, T], error) {
+	text, err := unmarshalJSONText[K](doc.Text)
+	if err != nil {
+		return nil, err
+	}
+	if isRoot != (len(text) == 0) {
+		return nil, fmt.Errorf("lradix: invalid node text %s", doc.Text)
+	}
+	node := &Node[K, T]{
+		Text: text,
+		Val:  doc.Value,
+		End:  doc.End,
+	}
+	for _, childDoc := range doc.Children {
+		if childDoc == nil {
+			return nil, fmt.Errorf("lradix: null child below node %s", doc.Text)
+		}
+		child, err := importJSONNode[K](childDoc, false)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := node.GetChild(child.Text[0]); ok {
+			return nil, fmt.Errorf("lradix: duplicate child %s below node %s", childDoc.Text, doc.Text)
+		}
+		node.AddChild(child)
+	}
+	return node, nil
+}
+
+// concurrentNodeFrom recursively converts a node and its children into concurrent nodes with fresh IDs.
+func concurrentNodeFrom[K comparable, T any](node *Node[K, T]) *ConcurrentNode[K, T] {
+	nc := NewConcurrentNode(node.Text, node.Val, node.End)
+	node.children.each(func(_ K, child *Node[K, T]) bool {
+		nc.AddChild(concurrentNodeFrom(child))
+		return true
+	})
+	return nc
+}
+
+// marshalJSONText renders a key or node text as a JSON string for []byte and []rune holding
+// valid UTF-8, and as a JSON array of its characters otherwise, like printNode does for display.
+// Invalid UTF-8 would be replaced by U+FFFD in a JSON string, merging distinct keys.
+func marshalJSONText[K comparable](text []K) (json.RawMessage, error) {
+	switch v := any(text).(type) {
+	case []byte:
+		if utf8.Valid(v) {
+			return json.Marshal(string(v))
+		}
+		// json.Marshal renders []byte as base64, render the bytes as numbers instead
+		chars := make([]int, len(v))
+		for i, b := range v {
+			chars[i] = int(b)
+		}
+		return json.Marshal(chars)
+	case []rune:
+		if validRunes(v) {
+			return json.Marshal(string(v))
+		}
+		return json.Marshal(v)
+	default:
+		if text == nil {
+			text = []K{}
+		}
+		return json.Marshal(text)
+	}
+}
+
+// unmarshalJSONText parses a key or node text rendered by marshalJSONText.
+func unmarshalJSONText[K comparable](raw json.RawMessage) ([]K, error) {
+	var text []K
+	switch any(text).(type) {
+	case []byte, []rune:
+		if trimmed := bytes.TrimLeft(raw, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+			break
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		if b, ok := any([]byte(s)).([]K); ok {
+			return b, nil
+		}
+		return any([]rune(s)).([]K), nil
+	}
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return nil, err
+	}
+	return text, nil
+}
+
+// validRunes reports whether every rune of text can be encoded as UTF-8.
+func validRunes(text []rune) bool {
+	for _, r := range text {
+		if !utf8.ValidRune(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,159 @@
+package lradix
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestExportJSONFlat(t *testing.T) {
+	tree := NewTree[byte, int]()
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2)
+
+	var b bytes.Buffer
+	if err := tree.ExportJSON(&b, JSONFlat); err != nil {
+		t.Fatalf("ExportJSON() error = %v", err)
+	}
+	expected := `[{"key":"hello","value":1},{"key":"help","value":2}]` + "\n"
+	if b.String() != expected {
+		t.Errorf("ExportJSON(JSONFlat) = %s, expected %s", b.String(), expected)
+	}
+
+	imported, err := ImportJSON[byte, int](&b, JSONFlat)
+	if err != nil {
+		t.Fatalf("ImportJSON() error = %v", err)
+	}
+	if imported.String() != tree.String() {
+		t.Errorf("Imported tree =\n%s\nexpected\n%s", imported.String(), tree.String())
+	}
+}
+
+func TestExportJSONNested(t *testing.T) {
+	tree := NewTree[int, string]()
+	tree.Insert([]int{1, 2, 3}, "a")
+	tree.Insert([]int{1, 2, 4}, "b")
+
+	var b bytes.Buffer
+	if err := tree.ExportJSON(&b, JSONNested); err != nil {
+		t.Fatalf("ExportJSON() error = %v", err)
+	}
+	expected := `{"text":[],"end":false,"value":null,"children":[` +
+		`{"text":[1,2],"end":false,"value":"b","children":[` +
+		`{"text":[3],"end":true,"value":"a"},` +
+		`{"text":[4],"end":true,"value":"b"}]}]}` + "\n"
+	if b.String() != expected {
+		t.Errorf("ExportJSON(JSONNested) = %s, expected %s", b.String(), expected)
+	}
+
+	imported, err := ImportJSON[int, string](&b, JSONNested)
+	if err != nil {
+		t.Fatalf("ImportJSON() error = %v", err)
+	}
+	if imported.String() != tree.String() {
+		t.Errorf("Imported tree =\n%s\nexpected\n%s", imported.String(), tree.String())
+	}
+	if node := imported.findNode([]int{1, 2, 4}); node == nil || node.Parent.Parent != imported.Root {
+		t.Error("Imported tree has broken parent pointers")
+	}
+}
+
+func TestJSONBinaryKeys(t *testing.T) {
+	tree := NewTree[byte, int]()
+	tree.Insert([]byte{0xff, 1}, 1)
+	tree.Insert([]byte{0xfe, 1}, 2)
+	tree.Insert([]byte("é"), 3) // splits with è in the middle of the encoded character
+	tree.Insert([]byte("è"), 4)
+	tree.Insert([]byte("plain"), 5)
+	runes := NewTree[rune, int]()
+	runes.Insert([]rune{0xd800, 'a'}, 1) // a surrogate half is no valid rune
+	runes.Insert([]rune{0xdfff, 'a'}, 2)
+	runes.Insert([]rune("你好"), 3)
+	// flat imports derive intermediate values from their own insertion order,
+	// so the trees are compared by their stored keys and values
+	flat := func(export func(w io.Writer, format JSONFormat) error) string {
+		var b bytes.Buffer
+		if err := export(&b, JSONFlat); err != nil {
+			t.Fatalf("ExportJSON() error = %v", err)
+		}
+		return b.String()
+	}
+
+	for _, format := range []JSONFormat{JSONFlat, JSONNested} {
+		var b bytes.Buffer
+		if err := tree.ExportJSON(&b, format); err != nil {
+			t.Fatalf("ExportJSON(%d) error = %v", format, err)
+		}
+		imported, err := ImportJSON[byte, int](&b, format)
+		if err != nil {
+			t.Fatalf("ImportJSON(%d) error = %v", format, err)
+		}
+		if imported.Len() != tree.Len() || flat(imported.ExportJSON) != flat(tree.ExportJSON) {
+			t.Errorf("ImportJSON(%d) = %d keys\n%s\nexpected %d keys\n%s", format, imported.Len(), imported.String(), tree.Len(), tree.String())
+		}
+		if format == JSONNested && imported.String() != tree.String() {
+			t.Errorf("ImportJSON(JSONNested) =\n%s\nexpected the node layout\n%s", imported.String(), tree.String())
+		}
+		if val, ok := imported.Get([]byte{0xfe, 1}); !ok || *val != 2 {
+			t.Errorf("Get(fe 01) = %v, %v after ImportJSON(%d), expected 2, true", val, ok, format)
+		}
+
+		b.Reset()
+		if err := runes.ExportJSON(&b, format); err != nil {
+			t.Fatalf("ExportJSON(%d) error = %v", format, err)
+		}
+		importedRunes, err := ImportJSON[rune, int](&b, format)
+		if err != nil {
+			t.Fatalf("ImportJSON(%d) error = %v", format, err)
+		}
+		if importedRunes.Len() != runes.Len() || flat(importedRunes.ExportJSON) != flat(runes.ExportJSON) {
+			t.Errorf("ImportJSON(%d) of rune keys = %d keys\n%s\nexpected %d keys\n%s", format, importedRunes.Len(), importedRunes.String(), runes.Len(), runes.String())
+		}
+	}
+
+	if out := flat(tree.ExportJSON); !strings.Contains(out, `{"key":[255,1],"value":1}`) || !strings.Contains(out, `{"key":"plain","value":5}`) {
+		t.Errorf("ExportJSON(JSONFlat) = %s, expected only invalid UTF-8 keys rendered as arrays", out)
+	}
+}
+
+func TestImportJSONInvalid(t *testing.T) {
+	testCases := []struct {
+		format JSONFormat
+		input  string
+	}{
+		{JSONFlat, `{"key":"a"}`},
+		{JSONFlat, `[{"key":1,"value":1}]`},                                             // byte keys must be strings
+		{JSONNested, `{"text":"a","end":true}`},                                         // root with text
+		{JSONNested, `{"text":"","children":[{"text":"","end":true}]}`},                 // empty child text
+		{JSONNested, `{"text":"","children":[{"text":"ab","end":true},{"text":"ac"}]}`}, // duplicate head
+		{JSONNested, `{"text":"","children":[null]}`},                                   // null child
+		{JSONFormat(7), `[]`},
+	}
+	for _, tc := range testCases {
+		if _, err := ImportJSON[byte, int](strings.NewReader(tc.input), tc.format); err == nil {
+			t.Errorf("ImportJSON(%s) should fail", tc.input)
+		}
+	}
+}
+
+func TestConcurrentTreeJSON(t *testing.T) {
+	tree := NewConcurrentTree[rune, int]()
+	tree.Insert([]rune("你好"), 1)
+	tree.Insert([]rune("你们"), 2)
+
+	var b bytes.Buffer
+	if err := tree.ExportJSON(&b, JSONNested); err != nil {
+		t.Fatalf("ExportJSON() error = %v", err)
+	}
+	if !strings.Contains(b.String(), `"text":"你"`) {
+		t.Errorf("ExportJSON(JSONNested) = %s, expected rune text rendered as a string", b.String())
+	}
+	imported, err := ImportConcurrentJSON[rune, int](&b, JSONNested)
+	if err != nil {
+		t.Fatalf("ImportConcurrentJSON() error = %v", err)
+	}
+	if result, ok := imported.Get([]rune("你们")); !ok || *result != 2 {
+		t.Errorf("Get(你们) = %v, %v after import, expected 2, true", result, ok)
+	}
+}
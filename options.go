@@ -0,0 +1,50 @@
+package lradix
+
+import (
+	"slices"
+	"time"
+)
+
+// Option configures a tree created by NewTree or NewConcurrentTree.
+type Option func(*treeOptions)
+
+// treeOptions holds the settings applied by Options. The zero value is the default
+// configuration, which is also used by trees decoded or imported from other formats.
+type treeOptions struct {
+	zeroCopy     bool             // store key fragments as subslices of the caller's keys
+	valuePolicy  ValuePolicy      // how intermediate nodes derive their value
+	valueReducer any              // func(children []*T) *T for ValueReduce, typed by newValuePolicy
+	aggregator   any              // Aggregator[T, A] for subtree summaries, typed by newAggregator
+	maxKeys      int              // ConcurrentTree capacity in keys, zero for unbounded
+	maxElements  int              // ConcurrentTree capacity in node text elements, zero for unbounded
+	onEvict      any              // func(key []K, val *T) called for evicted entries, typed by newEvictCallback
+	ttl          time.Duration    // default TTL of values inserted into a ConcurrentTree, zero if they never expire
+	clock        func() time.Time // current time for expiry, time.Now if nil
+}
+
+func newTreeOptions(opts []Option) treeOptions {
+	var o treeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithZeroCopy makes Insert store key fragments as subslices of the caller's key instead
+// of copying them into the tree's own storage. It saves an allocation per inserted key,
+// but the caller must never modify a key slice after passing it to Insert, or the tree
+// is silently corrupted.
+func WithZeroCopy() Option {
+	return func(o *treeOptions) {
+		o.zeroCopy = true
+	}
+}
+
+// ownText returns the fragment of an inserted key to store as a node's text:
+// the fragment itself in zero-copy mode, a copy otherwise.
+func ownText[K comparable](o treeOptions, text []K) []K {
+	if o.zeroCopy {
+		return text
+	}
+	return slices.Clone(text)
+}
@@ -0,0 +1,240 @@
+package lradix
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// WalkOrdered calls fn for every stored key in the tree in lexicographic order, along with its value.
+// A key is visited before any longer key it is a prefix of. Iteration stops early when fn returns false.
+func WalkOrdered[K cmp.Ordered, T any](t *Tree[K, T], fn func(key []K, val *T) bool) {
+	walkNode(t.Root, []K{}, cmp.Compare[K], fn)
+}
+
+// WalkConcurrentOrdered calls fn for every stored key in the concurrent tree in lexicographic order,
+// along with its value. Nodes are read-locked one at a time and no lock is held while fn runs,
+// so the walk is not a consistent snapshot under concurrent writes.
+// Iteration stops early when fn returns false.
+func WalkConcurrentOrdered[K cmp.Ordered, T any](t *ConcurrentTree[K, T], fn func(key []K, val *T) bool) {
+	walkConcurrentNode(t.Root, []K{}, cmp.Compare[K], t.expiryClock(), fn)
+}
+
+// childOrder returns the first characters indexing children, sorted by compare.
+// If compare is nil the keys are returned in storage order.
+func childOrder[K comparable, C comparable](c *children[K, C], compare func(a, b K) int) []K {
+	heads := make([]K, 0, c.len())
+	c.each(func(head K, _ C) bool {
+		heads = append(heads, head)
+		return true
+	})
+	if compare != nil {
+		slices.SortFunc(heads, compare)
+	}
+	return heads
+}
+
+// eachChild calls fn for every child in the order given by compare, or in storage order
+// if compare is nil. It returns false if fn stopped the iteration.
+func eachChild[K comparable, C comparable](c *children[K, C], compare func(a, b K) int, fn func(child C) bool) bool {
+	if compare == nil {
+		return c.each(func(_ K, child C) bool {
+			return fn(child)
+		})
+	}
+	for _, head := range childOrder(c, compare) {
+		child, _ := c.get(head)
+		if !fn(child) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareStable orders characters of any comparable type deterministically.
+// Integer, float and string kinds compare by value; any other type falls back
+// to comparing the fmt representation, which is stable but not meaningful.
+func compareStable[K comparable](a, b K) int {
+	switch va := any(a).(type) {
+	case byte:
+		return cmp.Compare(va, any(b).(byte))
+	case rune:
+		return cmp.Compare(va, any(b).(rune))
+	case int:
+		return cmp.Compare(va, any(b).(int))
+	case string:
+		return strings.Compare(va, any(b).(string))
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.IsValid() && vb.IsValid() && va.Kind() == vb.Kind() {
+		switch va.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return cmp.Compare(va.Int(), vb.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return cmp.Compare(va.Uint(), vb.Uint())
+		case reflect.Float32, reflect.Float64:
+			return cmp.Compare(va.Float(), vb.Float())
+		case reflect.String:
+			return strings.Compare(va.String(), vb.String())
+		}
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+// Iterator walks the stored keys of a Tree in lexicographic order.
+// It is created by Seek and reads the tree lazily, so the tree must not be
+// modified while the iterator is in use.
+type Iterator[K cmp.Ordered, T any] struct {
+	stack []iteratorFrame[K, T] // pending subtrees, the next one to visit on top
+}
+
+// iteratorFrame is a subtree waiting to be visited by an Iterator.
+type iteratorFrame[K comparable, T any] struct {
+	node      *Node[K, T]
+	parentKey []K // reconstructed key up to, but not including, node's own text
+}
+
+// Next returns the next key in lexicographic order along with its value.
+// The boolean is false once the iterator is exhausted.
+func (it *Iterator[K, T]) Next() ([]K, *T, bool) {
+	for len(it.stack) > 0 {
+		frame := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+		node := frame.node
+		key := append(frame.parentKey[:len(frame.parentKey):len(frame.parentKey)], node.Text...)
+		it.pushChildren(node, key, nil)
+		if node.End {
+			return key, node.Val, true
+		}
+	}
+	return nil, nil, false
+}
+
+// pushChildren pushes the children of node whose first character is greater than after,
+// or all children if after is nil, so that the smallest one is visited first.
+func (it *Iterator[K, T]) pushChildren(node *Node[K, T], key []K, after *K) {
+	heads := childOrder(&node.children, cmp.Compare[K])
+	for i := len(heads) - 1; i >= 0; i-- {
+		if after != nil && heads[i] <= *after {
+			break
+		}
+		child, _ := node.GetChild(heads[i])
+		it.stack = append(it.stack, iteratorFrame[K, T]{node: child, parentKey: key})
+	}
+}
+
+// Seek returns an iterator positioned at the first stored key that is greater than or equal to key.
+// Only the children along the search path are sorted, so no full copy of the keys is made.
+func Seek[K cmp.Ordered, T any](t *Tree[K, T], key []K) *Iterator[K, T] {
+	it := &Iterator[K, T]{}
+	node := t.Root
+	parentKey := []K{}
+	search := key
+	for {
+		l := min(len(node.Text), len(search))
+		switch c := slices.Compare(node.Text[:l], search[:l]); {
+		case c < 0:
+			// the whole subtree sorts before key
+			return it
+		case c > 0 || len(search) <= len(node.Text):
+			// the whole subtree sorts at or after key
+			it.stack = append(it.stack, iteratorFrame[K, T]{node: node, parentKey: parentKey})
+			return it
+		}
+		// node's own key is a proper prefix of key and sorts before it
+		nodeKey := append(parentKey[:len(parentKey):len(parentKey)], node.Text...)
+		search = search[len(node.Text):]
+		it.pushChildren(node, nodeKey, &search[0])
+		next, ok := node.GetChild(search[0])
+		if !ok {
+			return it
+		}
+		node = next
+		parentKey = nodeKey
+	}
+}
+
+// Ceiling returns the smallest stored key that is greater than or equal to key, along with its value.
+func Ceiling[K cmp.Ordered, T any](t *Tree[K, T], key []K) ([]K, *T, bool) {
+	return Seek(t, key).Next()
+}
+
+// Floor returns the largest stored key that is less than or equal to key, along with its value.
+func Floor[K cmp.Ordered, T any](t *Tree[K, T], key []K) ([]K, *T, bool) {
+	return floorNode(t.Root, []K{}, key)
+}
+
+// Minimum returns the smallest stored key in lexicographic order, along with its value.
+func Minimum[K cmp.Ordered, T any](t *Tree[K, T]) ([]K, *T, bool) {
+	return Seek(t, nil).Next()
+}
+
+// Maximum returns the largest stored key in lexicographic order, along with its value.
+func Maximum[K cmp.Ordered, T any](t *Tree[K, T]) ([]K, *T, bool) {
+	return maxNode(t.Root, []K{})
+}
+
+// floorNode returns the largest key in node's subtree that is less than or equal to search,
+// where search is the remainder of the query after parentKey.
+func floorNode[K cmp.Ordered, T any](node *Node[K, T], parentKey []K, search []K) ([]K, *T, bool) {
+	l := min(len(node.Text), len(search))
+	switch c := slices.Compare(node.Text[:l], search[:l]); {
+	case c < 0:
+		// the whole subtree sorts before the query
+		return maxNode(node, parentKey)
+	case c > 0 || len(search) < len(node.Text):
+		// the whole subtree sorts after the query
+		return nil, nil, false
+	}
+	key := append(parentKey[:len(parentKey):len(parentKey)], node.Text...)
+	if len(search) == len(node.Text) {
+		// node holds the query itself and all descendants sort after it
+		if node.End {
+			return key, node.Val, true
+		}
+		return nil, nil, false
+	}
+	rest := search[len(node.Text):]
+	heads := childOrder(&node.children, cmp.Compare[K])
+	for i := len(heads) - 1; i >= 0; i-- {
+		var (
+			k  []K
+			v  *T
+			ok bool
+		)
+		child, _ := node.GetChild(heads[i])
+		switch {
+		case heads[i] == rest[0]:
+			k, v, ok = floorNode(child, key, rest)
+		case heads[i] < rest[0]:
+			k, v, ok = maxNode(child, key)
+		}
+		if ok {
+			return k, v, true
+		}
+	}
+	if node.End {
+		// node's key is a proper prefix of the query
+		return key, node.Val, true
+	}
+	return nil, nil, false
+}
+
+// maxNode returns the largest key in node's subtree. Longer keys sort after their prefixes,
+// so the largest child is tried first and node itself last.
+func maxNode[K cmp.Ordered, T any](node *Node[K, T], parentKey []K) ([]K, *T, bool) {
+	key := append(parentKey[:len(parentKey):len(parentKey)], node.Text...)
+	heads := childOrder(&node.children, cmp.Compare[K])
+	for i := len(heads) - 1; i >= 0; i-- {
+		child, _ := node.GetChild(heads[i])
+		if k, v, ok := maxNode(child, key); ok {
+			return k, v, true
+		}
+	}
+	if node.End {
+		return key, node.Val, true
+	}
+	return nil, nil, false
+}
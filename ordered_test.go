@@ -0,0 +1,230 @@
+package lradix
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestWalkOrdered(t *testing.T) {
+	tree := NewTree[byte, int]()
+	keys := []string{"tackle", "team", "a", "test", "taco", "toast", "ab", "t"}
+	for i, key := range keys {
+		tree.Insert([]byte(key), i)
+	}
+
+	got := []string{}
+	WalkOrdered(tree, func(key []byte, val *int) bool {
+		got = append(got, string(key))
+		return true
+	})
+	expected := "a,ab,t,tackle,taco,team,test,toast"
+	if strings.Join(got, ",") != expected {
+		t.Errorf("WalkOrdered = %v, expected %s", got, expected)
+	}
+
+	// Early stop
+	got = got[:0]
+	WalkOrdered(tree, func(key []byte, val *int) bool {
+		got = append(got, string(key))
+		return len(got) < 3
+	})
+	if strings.Join(got, ",") != "a,ab,t" {
+		t.Errorf("WalkOrdered with early stop = %v, expected [a ab t]", got)
+	}
+
+	// Unordered walk visits the same keys
+	count := 0
+	tree.Walk(func(key []byte, val *int) bool {
+		count++
+		return true
+	})
+	if count != len(keys) {
+		t.Errorf("Walk visited %d keys, expected %d", count, len(keys))
+	}
+}
+
+func TestWalkConcurrentOrdered(t *testing.T) {
+	tree := NewConcurrentTree[int, string]()
+	tree.Insert([]int{10, 2}, "b")
+	tree.Insert([]int{9}, "a")
+	tree.Insert([]int{10}, "c")
+	tree.Insert([]int{10, 1, 5}, "d")
+
+	got := []string{}
+	WalkConcurrentOrdered(tree, func(key []int, val *string) bool {
+		got = append(got, *val)
+		return true
+	})
+	// {9} < {10} < {10 1 5} < {10 2}
+	if strings.Join(got, ",") != "a,c,d,b" {
+		t.Errorf("WalkConcurrentOrdered = %v, expected [a c d b]", got)
+	}
+}
+
+func TestStringStableOrder(t *testing.T) {
+	build := func(keys []string) string {
+		tree := NewTree[byte, int]()
+		for _, key := range keys {
+			tree.Insert([]byte(key), len(key))
+		}
+		return tree.String()
+	}
+
+	expected := "└──ROOT (val: nil)\n" +
+		"   └──a (val: 1)\n" +
+		"   └──b (val: 2)\n" +
+		"      └──a (val: 2)\n" +
+		"      └──c (val: 2)\n" +
+		"   └──z (val: 1)\n"
+	for i := 0; i < 10; i++ {
+		if s := build([]string{"z", "bc", "a", "ba"}); s != expected {
+			t.Fatalf("String() =\n%s\nexpected\n%s", s, expected)
+		}
+	}
+
+	ctree := NewConcurrentTree[rune, int]()
+	for _, key := range []string{"z", "bc", "a", "ba"} {
+		ctree.Insert([]rune(key), len(key))
+	}
+	if s := ctree.String(); s != expected {
+		t.Errorf("ConcurrentTree.String() =\n%s\nexpected\n%s", s, expected)
+	}
+}
+
+func TestCompareStable(t *testing.T) {
+	type point struct{ x, y int }
+	testCases := []struct {
+		result   int
+		expected int
+	}{
+		{compareStable(9, 10), -1}, // numeric, not textual, order
+		{compareStable(uint8(200), uint8(3)), 1},
+		{compareStable(1.5, 1.5), 0},
+		{compareStable("b", "a"), 1},
+		{compareStable(point{1, 2}, point{1, 3}), -1}, // fmt fallback
+	}
+	for i, tc := range testCases {
+		if tc.result != tc.expected {
+			t.Errorf("case %d: compareStable = %d, expected %d", i, tc.result, tc.expected)
+		}
+	}
+}
+
+func TestSeekFloorCeiling(t *testing.T) {
+	tree := NewTree[byte, int]()
+	keys := []string{"b", "ba", "bab", "bb", "d", "dz", "f"}
+	for i, key := range keys {
+		tree.Insert([]byte(key), i)
+	}
+
+	testCases := []struct {
+		query   string
+		ceiling string // "" for none
+		floor   string // "" for none
+	}{
+		{"", "b", ""},
+		{"a", "b", ""},
+		{"b", "b", "b"},
+		{"ba", "ba", "ba"},
+		{"baa", "bab", "ba"},
+		{"bab", "bab", "bab"},
+		{"babz", "bb", "bab"},
+		{"bc", "d", "bb"},
+		{"c", "d", "bb"},
+		{"d", "d", "d"},
+		{"da", "dz", "d"},
+		{"dzz", "f", "dz"},
+		{"e", "f", "dz"},
+		{"f", "f", "f"},
+		{"g", "", "f"},
+	}
+
+	for _, tc := range testCases {
+		key, _, ok := Ceiling(tree, []byte(tc.query))
+		if ok != (tc.ceiling != "") || string(key) != tc.ceiling {
+			t.Errorf("Ceiling(%q) = %q, %v, expected %q", tc.query, key, ok, tc.ceiling)
+		}
+		key, _, ok = Floor(tree, []byte(tc.query))
+		if ok != (tc.floor != "") || string(key) != tc.floor {
+			t.Errorf("Floor(%q) = %q, %v, expected %q", tc.query, key, ok, tc.floor)
+		}
+	}
+
+	// Seek continues in order after the first key
+	it := Seek(tree, []byte("bac"))
+	got := []string{}
+	for key, _, ok := it.Next(); ok; key, _, ok = it.Next() {
+		got = append(got, string(key))
+	}
+	if strings.Join(got, ",") != "bb,d,dz,f" {
+		t.Errorf("Seek(bac) = %v, expected [bb d dz f]", got)
+	}
+
+	if key, _, ok := Minimum(tree); !ok || string(key) != "b" {
+		t.Errorf("Minimum = %q, %v, expected b", key, ok)
+	}
+	if key, _, ok := Maximum(tree); !ok || string(key) != "f" {
+		t.Errorf("Maximum = %q, %v, expected f", key, ok)
+	}
+}
+
+func TestMinimumMaximumEmptyTree(t *testing.T) {
+	tree := NewTree[byte, int]()
+	if _, _, ok := Minimum(tree); ok {
+		t.Error("Minimum of empty tree should fail")
+	}
+	if _, _, ok := Maximum(tree); ok {
+		t.Error("Maximum of empty tree should fail")
+	}
+	if _, _, ok := Floor(tree, []byte("x")); ok {
+		t.Error("Floor of empty tree should fail")
+	}
+	if _, _, ok := Seek(tree, []byte("x")).Next(); ok {
+		t.Error("Seek in empty tree should be exhausted")
+	}
+}
+
+func TestSeekMatchesSortedKeys(t *testing.T) {
+	tree := NewTree[byte, int]()
+	rng := rand.New(rand.NewSource(1))
+	randomKey := func() []byte {
+		key := make([]byte, 1+rng.Intn(5))
+		for i := range key {
+			key[i] = "abc"[rng.Intn(3)]
+		}
+		return key
+	}
+	stored := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		key := randomKey()
+		tree.Insert(key, i)
+		stored[string(key)] = true
+	}
+	sorted := make([]string, 0, len(stored))
+	for key := range stored {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for i := 0; i < 200; i++ {
+		query := string(randomKey())
+		idx := sort.SearchStrings(sorted, query)
+		ceiling, floor := "", ""
+		if idx < len(sorted) {
+			ceiling = sorted[idx]
+		}
+		if idx < len(sorted) && sorted[idx] == query {
+			floor = query
+		} else if idx > 0 {
+			floor = sorted[idx-1]
+		}
+		if key, _, _ := Ceiling(tree, []byte(query)); string(key) != ceiling {
+			t.Errorf("Ceiling(%q) = %q, expected %q", query, key, ceiling)
+		}
+		if key, _, _ := Floor(tree, []byte(query)); string(key) != floor {
+			t.Errorf("Floor(%q) = %q, expected %q", query, key, floor)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package lradix
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// OwnerTree tracks which owners, such as the workers of a cache-aware router, hold each prefix.
+// Every node knows the owners of the keys in its subtree along with the time each of them last
+// inserted one, so an owner holds every prefix of the keys it inserted. It is built on a
+// ConcurrentTree whose values are the owners of each key, and every node counts the keys each
+// owner holds below it, so a write only updates the counts along its own path.
+// Lookups run concurrently with everything else, while writers are serialized.
+type OwnerTree[K comparable, O comparable] struct {
+	tree    *ConcurrentTree[K, owners[O]]
+	mu      sync.Mutex    // serializes writers, which replace the owners of a key as a whole
+	evicted []owned[K, O] // entries evicted by the Insert in progress, guarded by mu
+}
+
+// OwnerMatch describes how much of a key an owner holds, as reported by MatchOwners.
+type OwnerMatch struct {
+	Length     int       // length of the longest prefix of the key held by the owner
+	LastAccess time.Time // last time the owner inserted a key starting with that prefix
+}
+
+// owners maps owners to the time they last inserted a key. Maps are never modified once
+// stored in the tree, so they can be read without locks.
+type owners[O comparable] map[O]time.Time
+
+// owned is a key along with its owners.
+type owned[K comparable, O comparable] struct {
+	key    []K
+	owners owners[O]
+}
+
+// ownerCount is the number of keys an owner holds in a subtree and the last time it inserted one.
+type ownerCount struct {
+	keys int
+	at   time.Time
+}
+
+// ownerCounts is kept in the summary of every node but the root. It is modified in place under
+// the node's write lock, so readers must hold the node's read lock while reading it.
+type ownerCounts[O comparable] map[O]ownerCount
+
+// add counts n more keys held by owner, last inserted at at.
+func (c ownerCounts[O]) add(owner O, n int, at time.Time) {
+	prev := c[owner]
+	if at.Before(prev.at) {
+		at = prev.at
+	}
+	c[owner] = ownerCount{keys: prev.keys + n, at: at}
+}
+
+// remove counts one key less held by owner, forgetting owners that hold no key anymore.
+func (c ownerCounts[O]) remove(owner O) {
+	prev, ok := c[owner]
+	if !ok {
+		return
+	}
+	if prev.keys <= 1 {
+		delete(c, owner)
+		return
+	}
+	c[owner] = ownerCount{keys: prev.keys - 1, at: prev.at}
+}
+
+// NewOwnerTree creates an empty OwnerTree. The options are those of NewConcurrentTree;
+// WithClock sets the clock used for access times, while value policies and eviction
+// callbacks are managed by the OwnerTree itself. Keys are only removed by RemoveOwner
+// and by eviction, so WithTTL is ignored.
+func NewOwnerTree[K comparable, O comparable](opts ...Option) *OwnerTree[K, O] {
+	t := &OwnerTree[K, O]{}
+	opts = append(opts[:len(opts):len(opts)],
+		WithValuePolicy(ValueNone),
+		WithTTL(0),
+		WithEvictCallback(func(key []K, val *owners[O]) {
+			t.evicted = append(t.evicted, owned[K, O]{key, *val})
+		}),
+	)
+	t.tree = NewConcurrentTree[K, owners[O]](opts...)
+	return t
+}
+
+// Insert adds owner to the owners of key and of every prefix of it, and records the current time
+// as the owner's last access along that path.
+func (t *OwnerTree[K, O]) Insert(key []K, owner O) {
+	if len(key) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.tree.now()
+	prev, existed := t.tree.Get(key)
+	next := owners[O]{}
+	if existed {
+		next = maps.Clone(*prev)
+	}
+	_, held := next[owner]
+	next[owner] = now
+	t.tree.Insert(key, next)
+
+	stored := true
+	for _, e := range t.evicted {
+		if !slices.Equal(e.key, key) {
+			t.release(e.key, e.owners)
+			continue
+		}
+		// evicted by its own insert, before the new owner was counted
+		stored = false
+		if existed {
+			t.release(e.key, *prev)
+		}
+	}
+	t.evicted = nil
+	if !stored {
+		return
+	}
+	path := t.path(key)
+	for i := len(path) - 1; i >= 0; i-- {
+		node := path[i]
+		node.Lock()
+		if counts, ok := node.summary.(ownerCounts[O]); !ok {
+			// created by this insert
+			node.summary = countOwners(node)
+		} else if !held {
+			counts.add(owner, 1, now)
+		} else {
+			counts.add(owner, 0, now)
+		}
+		node.Unlock()
+	}
+}
+
+// path returns the nodes matching key, from the child of the root down, ending with the node
+// of key itself if it is stored. The caller must hold t.mu.
+func (t *OwnerTree[K, O]) path(key []K) []*ConcurrentNode[K, owners[O]] {
+	var path []*ConcurrentNode[K, owners[O]]
+	mark := t.tree.Root
+	index := 0
+	for index < len(key) {
+		mark.RLock()
+		next, ok := mark.GetChild(key[index])
+		mark.RUnlock()
+		if !ok {
+			break
+		}
+		next.RLock()
+		sharedPrefix := longestPrefix(next.Text, key[index:])
+		textLength := len(next.Text)
+		next.RUnlock()
+		if sharedPrefix < textLength {
+			break
+		}
+		path = append(path, next)
+		index += sharedPrefix
+		mark = next
+	}
+	return path
+}
+
+// release stops counting the owners of a key that is no longer stored, or no longer held by them,
+// in the nodes matching it. Nodes created by the Insert in progress are counted afterwards.
+// The caller must hold t.mu.
+func (t *OwnerTree[K, O]) release(key []K, held owners[O]) {
+	for _, node := range t.path(key) {
+		node.Lock()
+		if counts, ok := node.summary.(ownerCounts[O]); ok {
+			for owner := range held {
+				counts.remove(owner)
+			}
+		}
+		node.Unlock()
+	}
+}
+
+// countOwners counts the owners of a node created by an Insert from its own owners and the counts
+// of its children. The caller must hold the node's write lock.
+func countOwners[K comparable, O comparable](node *ConcurrentNode[K, owners[O]]) ownerCounts[O] {
+	counts := ownerCounts[O]{}
+	if node.End {
+		for owner, at := range *node.Val {
+			counts.add(owner, 1, at)
+		}
+	}
+	node.children.each(func(_ K, child *ConcurrentNode[K, owners[O]]) bool {
+		child.RLock()
+		childCounts, _ := child.summary.(ownerCounts[O])
+		for owner, c := range childCounts {
+			counts.add(owner, c.keys, c.at)
+		}
+		child.RUnlock()
+		return true
+	})
+	return counts
+}
+
+// MatchOwners returns every owner holding a non-empty prefix of key, along with the length of
+// the longest such prefix. A prefix ending inside a node's text is held by the owners of that
+// node's subtree. Nodes are read-locked one at a time, as in LongestCommonPrefixMatch.
+func (t *OwnerTree[K, O]) MatchOwners(key []K) map[O]OwnerMatch {
+	matches := map[O]OwnerMatch{}
+	mark := t.tree.Root
+	index := 0
+	for index < len(key) {
+		mark.RLock()
+		next, ok := mark.GetChild(key[index])
+		mark.RUnlock()
+		if !ok {
+			break
+		}
+		next.RLock()
+		sharedPrefix := longestPrefix(next.Text, key[index:])
+		textLength := len(next.Text)
+		index += sharedPrefix
+		// owners below a deeper node also hold every shorter prefix, so deeper nodes overwrite
+		counts, _ := next.summary.(ownerCounts[O])
+		for owner, c := range counts {
+			matches[owner] = OwnerMatch{Length: index, LastAccess: c.at}
+		}
+		next.RUnlock()
+		if sharedPrefix < textLength {
+			// partial match, stop
+			break
+		}
+		mark = next
+	}
+	return matches
+}
+
+// RemoveOwner strips owner from every key and returns the number of keys it held. Keys left
+// without owners are removed, along with the nodes that only served them.
+func (t *OwnerTree[K, O]) RemoveOwner(owner O) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	type held struct {
+		key    []K
+		others owners[O]
+	}
+	var found []held
+	t.tree.Walk(func(key []K, val *owners[O]) bool {
+		if _, ok := (*val)[owner]; ok {
+			others := maps.Clone(*val)
+			delete(others, owner)
+			found = append(found, held{key, others})
+		}
+		return true
+	})
+	for _, h := range found {
+		t.release(h.key, owners[O]{owner: time.Time{}})
+		if len(h.others) == 0 {
+			t.tree.Delete(h.key)
+		} else {
+			t.tree.Insert(h.key, h.others)
+		}
+		for _, e := range t.evicted {
+			t.release(e.key, e.owners)
+		}
+		t.evicted = nil
+	}
+	return len(found)
+}
+
+// Len returns the number of keys held by at least one owner.
+func (t *OwnerTree[K, O]) Len() int {
+	return t.tree.Len()
+}
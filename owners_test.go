@@ -0,0 +1,176 @@
+package lradix
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOwnerTree(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tree := NewOwnerTree[byte, string](WithClock(clock.Now))
+	tree.Insert([]byte("hello world"), "w1")
+	clock.Advance(time.Second)
+	tree.Insert([]byte("hello there"), "w2")
+	clock.Advance(time.Second)
+	tree.Insert([]byte("help"), "w3")
+	clock.Advance(time.Second)
+	tree.Insert([]byte("hello"), "w1")
+
+	at := func(seconds int64) time.Time { return time.Unix(1000+seconds, 0) }
+	testCases := []struct {
+		key      string
+		expected map[string]OwnerMatch
+	}{
+		{"hello world!", map[string]OwnerMatch{"w1": {11, at(0)}, "w2": {6, at(1)}, "w3": {3, at(2)}}},
+		{"hello", map[string]OwnerMatch{"w1": {5, at(3)}, "w2": {5, at(1)}, "w3": {3, at(2)}}},
+		{"hello wo", map[string]OwnerMatch{"w1": {8, at(0)}, "w2": {6, at(1)}, "w3": {3, at(2)}}},
+		{"he", map[string]OwnerMatch{"w1": {2, at(3)}, "w2": {2, at(1)}, "w3": {2, at(2)}}},
+		{"xyz", map[string]OwnerMatch{}},
+	}
+	for _, tc := range testCases {
+		if got := tree.MatchOwners([]byte(tc.key)); !maps.Equal(got, tc.expected) {
+			t.Errorf("MatchOwners(%q) = %v, expected %v", tc.key, got, tc.expected)
+		}
+	}
+
+	if n := tree.RemoveOwner("w1"); n != 2 {
+		t.Errorf("RemoveOwner(w1) = %d, expected 2", n)
+	}
+	if got, expected := tree.MatchOwners([]byte("hello world")), map[string]OwnerMatch{"w2": {6, at(1)}, "w3": {3, at(2)}}; !maps.Equal(got, expected) {
+		t.Errorf("MatchOwners(hello world) = %v after RemoveOwner, expected %v", got, expected)
+	}
+	if tree.Len() != 2 {
+		t.Errorf("Len() = %d after RemoveOwner, expected 2", tree.Len())
+	}
+	if err := tree.tree.Validate(); err != nil {
+		t.Error(err)
+	}
+	checkOwnerCounts(t, tree)
+
+	// a key shared by several owners is kept until its last owner is removed
+	tree.Insert([]byte("help"), "w2")
+	tree.RemoveOwner("w3")
+	if got := tree.MatchOwners([]byte("help")); got["w2"].Length != 4 {
+		t.Errorf("MatchOwners(help) = %v, expected w2 to keep help", got)
+	}
+	tree.RemoveOwner("w2")
+	if tree.Len() != 0 || tree.tree.Stats().Nodes != 0 {
+		t.Errorf("Len() = %d with %d nodes after removing every owner, expected an empty tree", tree.Len(), tree.tree.Stats().Nodes)
+	}
+	if n := tree.RemoveOwner("w2"); n != 0 {
+		t.Errorf("RemoveOwner of an unknown owner = %d, expected 0", n)
+	}
+}
+
+func TestOwnerTreeConcurrent(t *testing.T) {
+	tree := NewOwnerTree[byte, int]()
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(owner int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				key := []byte(fmt.Sprintf("prefix/%d/%d", i%10, owner))
+				tree.Insert(key, owner)
+				tree.MatchOwners(key)
+				if i%50 == 49 && owner%2 == 1 {
+					tree.RemoveOwner(owner)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	got := tree.MatchOwners([]byte("prefix/3/0"))
+	for owner := 0; owner < 8; owner++ {
+		expected := len("prefix/3/")
+		if owner == 0 {
+			expected = len("prefix/3/0")
+		}
+		if owner%2 == 1 {
+			expected = 0
+		}
+		if got[owner].Length != expected {
+			t.Errorf("MatchOwners(prefix/3/0)[%d] = %d, expected %d", owner, got[owner].Length, expected)
+		}
+	}
+	if err := tree.tree.Validate(); err != nil {
+		t.Error(err)
+	}
+	checkOwnerCounts(t, tree)
+}
+
+func TestOwnerTreeEviction(t *testing.T) {
+	tree := NewOwnerTree[byte, string](WithMaxKeys(3), WithMaxElements(16))
+	tree.Insert([]byte("alpha"), "w1")
+	tree.Insert([]byte("alps"), "w2")
+	tree.Insert([]byte("alpha"), "w2")
+	tree.Insert([]byte("beta"), "w1")
+	checkOwnerCounts(t, tree)
+	tree.Insert([]byte("alpine"), "w3") // evicts alps, the least recently used leaf
+	checkOwnerCounts(t, tree)
+	if _, ok := tree.MatchOwners([]byte("alps"))["w2"]; !ok {
+		t.Error("MatchOwners(alps) lost w2, which still holds alpha")
+	}
+	tree.Insert([]byte("a key that is too long"), "w4") // too long for the element bound, evicts itself
+	checkOwnerCounts(t, tree)
+	if _, ok := tree.MatchOwners([]byte("a"))["w4"]; ok {
+		t.Error("MatchOwners(a) reports w4, whose only key was evicted")
+	}
+	if err := tree.tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+// checkOwnerCounts compares the owner counts of every node with the owners of the keys below it.
+func checkOwnerCounts[K comparable, O comparable](t *testing.T, tree *OwnerTree[K, O]) {
+	t.Helper()
+	var visit func(node *ConcurrentNode[K, owners[O]]) map[O]int
+	visit = func(node *ConcurrentNode[K, owners[O]]) map[O]int {
+		held := map[O]int{}
+		if node.End {
+			for owner := range *node.Val {
+				held[owner]++
+			}
+		}
+		node.ForEachChild(func(_ K, child *ConcurrentNode[K, owners[O]]) bool {
+			for owner, n := range visit(child) {
+				held[owner] += n
+			}
+			return true
+		})
+		if node != tree.tree.Root {
+			counts, _ := node.summary.(ownerCounts[O])
+			got := map[O]int{}
+			for owner, c := range counts {
+				got[owner] = c.keys
+			}
+			if !maps.Equal(got, held) {
+				t.Errorf("Node %s counts owners %v, expected %v", displayText(node.Text), got, held)
+			}
+		}
+		return held
+	}
+	visit(tree.tree.Root)
+}
+
+func BenchmarkOwnerTreeInsert(b *testing.B) {
+	for _, fanout := range []int{10, 1000, 10000} {
+		b.Run(fmt.Sprintf("fanout=%d", fanout), func(b *testing.B) {
+			tree := NewOwnerTree[int, int]()
+			for i := 0; i < fanout; i++ {
+				for owner := 0; owner < 16; owner++ {
+					tree.Insert([]int{i, 1, 2, 3}, owner)
+				}
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tree.Insert([]int{i % fanout, 1, 2, 3}, i%16)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package lradix
+
+import (
+	"slices"
+	"strings"
+)
+
+// PersistentTree is an immutable radix tree.
+// Insert and Delete never modify the receiver; they return a new tree that shares every
+// unchanged node with the old one (path copying). Old versions therefore stay valid and can be
+// read from any number of goroutines without locks while writers build and publish new versions,
+// for example through an atomic.Pointer.
+//
+// Nodes of a persistent tree may be shared between versions, so their Parent pointers are
+// always nil and they must never be modified by the caller.
+//
+// Intermediate nodes carry the most recently inserted value below them, as in a Tree created
+// with the default ValuePolicy, and removals merge nodes the way Tree.RemoveNode does.
+type PersistentTree[K comparable, T any] struct {
+	root *Node[K, T]
+	seq  uint64 // sequence number of the last Insert, ordering values for the value policy
+}
+
+// NewPersistentTree creates a new empty persistent radix tree with keys of type K and values of type T.
+func NewPersistentTree[K comparable, T any]() *PersistentTree[K, T] {
+	return &PersistentTree[K, T]{
+		root: &Node[K, T]{
+			Text: []K{},
+		},
+	}
+}
+
+// Root returns the root node of this version of the tree. It must be treated as read-only.
+func (t *PersistentTree[K, T]) Root() *Node[K, T] {
+	return t.root
+}
+
+// Txn starts a transaction based on this version of the tree.
+// Several mutations can be applied to the transaction and committed to a single new version.
+func (t *PersistentTree[K, T]) Txn() *Txn[K, T] {
+	return &Txn[K, T]{
+		root:     t.root,
+		seq:      t.seq,
+		writable: map[*Node[K, T]]struct{}{},
+	}
+}
+
+// Insert returns a new version of the tree with the key-value pair inserted.
+// Value semantics match Tree.Insert with the default ValuePolicy: intermediate nodes take the
+// most recent value, while nodes storing a key keep their own. The key is copied, so the caller
+// may reuse it afterwards. Inserting an empty key returns the receiver unchanged.
+func (t *PersistentTree[K, T]) Insert(str []K, val T) *PersistentTree[K, T] {
+	txn := t.Txn()
+	txn.Insert(str, val)
+	return txn.Commit()
+}
+
+// Delete returns a new version of the tree without the given key, along with the removed value.
+// Parent cleanup matches Tree.RemoveNode. If the key is not stored, the receiver is returned
+// unchanged together with nil and false.
+func (t *PersistentTree[K, T]) Delete(str []K) (*PersistentTree[K, T], *T, bool) {
+	txn := t.Txn()
+	old, ok := txn.Delete(str)
+	if !ok {
+		return t, nil, false
+	}
+	return txn.Commit(), old, true
+}
+
+// Get returns the value stored under exactly the given key.
+func (t *PersistentTree[K, T]) Get(str []K) (*T, bool) {
+	return t.view().Get(str)
+}
+
+// LongestCommonPrefixMatch finds the longest prefix in the tree that matches the given key.
+// It has the same results as Tree.LongestCommonPrefixMatch.
+func (t *PersistentTree[K, T]) LongestCommonPrefixMatch(str []K) ([]K, *T, bool) {
+	return t.view().LongestCommonPrefixMatch(str)
+}
+
+// WalkPrefix calls fn for every stored key that starts with the given prefix, along with its value.
+// Iteration stops early when fn returns false.
+func (t *PersistentTree[K, T]) WalkPrefix(prefix []K, fn func(key []K, val *T) bool) {
+	t.view().WalkPrefix(prefix, fn)
+}
+
+// Walk calls fn for every stored key in the tree, along with its value.
+// Iteration stops early when fn returns false.
+func (t *PersistentTree[K, T]) Walk(fn func(key []K, val *T) bool) {
+	t.view().Walk(fn)
+}
+
+// WalkPath calls fn for every stored key that is a prefix of str, from shortest to longest.
+// Iteration stops early when fn returns false.
+func (t *PersistentTree[K, T]) WalkPath(str []K, fn func(key []K, val *T) bool) {
+	t.view().WalkPath(str, fn)
+}
+
+// String returns a string representation of the tree structure.
+func (t *PersistentTree[K, T]) String() string {
+	var result strings.Builder
+	printNode(t.root, "", &result)
+	return result.String()
+}
+
+// view wraps the root in a Tree so that read-only Tree operations can be reused.
+// None of those operations follow Parent pointers or modify nodes.
+func (t *PersistentTree[K, T]) view() *Tree[K, T] {
+	return &Tree[K, T]{Root: t.root}
+}
+
+// Txn batches mutations of a PersistentTree and commits them to a new version.
+// Nodes copied by the transaction are modified in place until Commit, so a batch of
+// mutations copies every node at most once. A Txn is not safe for concurrent use.
+type Txn[K comparable, T any] struct {
+	root     *Node[K, T]
+	seq      uint64                   // sequence number of the last Insert
+	writable map[*Node[K, T]]struct{} // nodes created by this transaction since the last commit
+}
+
+// Commit returns the new version of the tree containing all mutations applied so far.
+// The transaction can keep being used afterwards; later mutations copy nodes again
+// so the committed version is never modified.
+func (txn *Txn[K, T]) Commit() *PersistentTree[K, T] {
+	txn.writable = map[*Node[K, T]]struct{}{}
+	return &PersistentTree[K, T]{root: txn.root, seq: txn.seq}
+}
+
+// Get returns the value stored under exactly the given key, including uncommitted mutations.
+func (txn *Txn[K, T]) Get(str []K) (*T, bool) {
+	return (&Tree[K, T]{Root: txn.root}).Get(str)
+}
+
+// Insert inserts a key-value pair into the transaction.
+// If the key already exists, it will be overwritten. Empty keys are ignored.
+// The key is copied, since its fragments are shared by every later version.
+func (txn *Txn[K, T]) Insert(str []K, val T) {
+	if len(str) == 0 {
+		return
+	}
+	txn.seq++
+	txn.root = txn.insert(txn.root, slices.Clone(str), &val, true)
+}
+
+// Delete removes exactly the given key from the transaction and returns its previous value.
+// Returns nil and false if the key is not stored.
+func (txn *Txn[K, T]) Delete(str []K) (*T, bool) {
+	if len(str) == 0 {
+		return nil, false
+	}
+	root, old, ok := txn.delete(txn.root, str, true)
+	if !ok {
+		return nil, false
+	}
+	txn.root = root
+	return old, true
+}
+
+// insert returns a writable copy of node with str, the remainder of the key below node, inserted.
+func (txn *Txn[K, T]) insert(node *Node[K, T], str []K, val *T, isRoot bool) *Node[K, T] {
+	nc := txn.writableNode(node)
+	next, ok := nc.GetChild(str[0])
+	sharedPrefix := 0
+	if ok {
+		sharedPrefix = longestPrefix(next.Text, str)
+	}
+	switch {
+	case !ok:
+		// no match, add new node to current children
+		nc.children.put(str[0], txn.newLeaf(str, val))
+	case sharedPrefix < len(next.Text):
+		// partial match, split node; the value policy fills in the common node's value
+		commonNode := txn.newNode(next.Text[:sharedPrefix], nil, false)
+		split := txn.writableNode(next)
+		split.Text = next.Text[sharedPrefix:]
+		commonNode.children.put(split.Text[0], split)
+		if sharedPrefix < len(str) {
+			newNode := txn.newLeaf(str[sharedPrefix:], val)
+			commonNode.children.put(newNode.Text[0], newNode)
+			txn.derive(commonNode)
+		} else {
+			commonNode.Val, commonNode.seq = val, txn.seq
+			commonNode.End = true
+		}
+		nc.children.put(str[0], commonNode)
+	case sharedPrefix == len(str):
+		// full match, overwrite
+		child := txn.writableNode(next)
+		child.Val, child.seq = val, txn.seq
+		child.End = true
+		nc.children.put(str[0], child)
+	default:
+		// full match, move to next node
+		nc.children.put(str[0], txn.insert(next, str[sharedPrefix:], val, false))
+	}
+	if !isRoot {
+		txn.derive(nc)
+	}
+	return nc
+}
+
+// delete removes str, the remainder of the key below node, and returns the replacement for node.
+// A nil replacement means node itself was removed by the parent cleanup, and an intermediate node
+// left with a single child is replaced by that child, as in Tree.RemoveNode.
+// Nothing is copied unless the key is found.
+func (txn *Txn[K, T]) delete(node *Node[K, T], str []K, isRoot bool) (*Node[K, T], *T, bool) {
+	next, ok := node.GetChild(str[0])
+	if !ok {
+		return node, nil, false
+	}
+	sharedPrefix := longestPrefix(next.Text, str)
+	if sharedPrefix < len(next.Text) {
+		return node, nil, false
+	}
+	var (
+		replacement *Node[K, T]
+		old         *T
+	)
+	if sharedPrefix == len(str) {
+		if !next.End {
+			return node, nil, false
+		}
+		old = next.Val
+		if next.children.len() > 0 {
+			// nodes with children only lose their End flag, as in RemoveNode
+			child := txn.writableNode(next)
+			child.End = false
+			replacement = txn.tidy(child)
+		}
+	} else {
+		replacement, old, ok = txn.delete(next, str[sharedPrefix:], false)
+		if !ok {
+			return node, nil, false
+		}
+	}
+
+	nc := txn.writableNode(node)
+	if replacement != nil {
+		nc.children.put(str[0], replacement)
+	} else {
+		nc.children.remove(str[0])
+	}
+	if isRoot {
+		// root node can't be removed and carries no value
+		return nc, old, true
+	}
+	if nc.children.len() == 0 && !nc.End {
+		return nil, old, true
+	}
+	return txn.tidy(nc), old, true
+}
+
+// tidy restores the invariants of a writable node whose children or End flag changed and returns
+// its replacement: the node's only child when a non-branching intermediate node is merged into it,
+// the node itself with its value derived again otherwise.
+func (txn *Txn[K, T]) tidy(nc *Node[K, T]) *Node[K, T] {
+	if !nc.End && nc.children.len() == 1 {
+		only, _ := nc.children.first()
+		child := txn.writableNode(only)
+		child.Text = concatText(nc.Text, child.Text)
+		return child
+	}
+	txn.derive(nc)
+	return nc
+}
+
+// derive sets the value of a writable intermediate node to the most recent value among its
+// children. End nodes keep the value stored under their key.
+func (txn *Txn[K, T]) derive(nc *Node[K, T]) {
+	if nc.End {
+		return
+	}
+	acc := valueAcc[T]{policy: &valuePolicy[T]{kind: ValueMostRecent}}
+	nc.children.each(func(_ K, child *Node[K, T]) bool {
+		acc.add(child.Val, child.seq)
+		return true
+	})
+	nc.Val, nc.seq = acc.result()
+}
+
+// newNode creates a node owned by this transaction.
+func (txn *Txn[K, T]) newNode(text []K, val *T, end bool) *Node[K, T] {
+	node := &Node[K, T]{
+		Text: text,
+		Val:  val,
+		End:  end,
+	}
+	txn.writable[node] = struct{}{}
+	return node
+}
+
+// newLeaf creates a node owned by this transaction storing the value of the current Insert.
+func (txn *Txn[K, T]) newLeaf(text []K, val *T) *Node[K, T] {
+	node := txn.newNode(text, val, true)
+	node.seq = txn.seq
+	return node
+}
+
+// writableNode returns node itself if it was created by this transaction,
+// or a shallow copy with its own children storage otherwise.
+func (txn *Txn[K, T]) writableNode(node *Node[K, T]) *Node[K, T] {
+	if _, ok := txn.writable[node]; ok {
+		return node
+	}
+	nc := txn.newNode(node.Text, node.Val, node.End)
+	nc.seq = node.seq
+	nc.children = node.children.clone()
+	return nc
+}
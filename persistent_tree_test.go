@@ -0,0 +1,200 @@
+package lradix
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPersistentTreeVersions(t *testing.T) {
+	v0 := NewPersistentTree[byte, int]()
+	v1 := v0.Insert([]byte("hello"), 1)
+	v2 := v1.Insert([]byte("help"), 2)
+	v3, old, ok := v2.Delete([]byte("hello"))
+	if !ok || *old != 1 {
+		t.Errorf("Delete(hello) = %v, %v, expected 1, true", old, ok)
+	}
+
+	testCases := []struct {
+		tree  *PersistentTree[byte, int]
+		key   string
+		found bool
+	}{
+		{v0, "hello", false},
+		{v1, "hello", true},
+		{v1, "help", false},
+		{v2, "hello", true},
+		{v2, "help", true},
+		{v3, "hello", false},
+		{v3, "help", true},
+	}
+	for i, tc := range testCases {
+		if _, found := tc.tree.Get([]byte(tc.key)); found != tc.found {
+			t.Errorf("case %d: Get(%q) found = %v, expected %v", i, tc.key, found, tc.found)
+		}
+	}
+
+	// Deleting a missing key returns the same version
+	if v4, _, ok := v3.Delete([]byte("world")); ok || v4 != v3 {
+		t.Error("Delete of a missing key should return the receiver unchanged")
+	}
+	// Empty keys are ignored
+	if v4 := v3.Insert([]byte{}, 9); v4.String() != v3.String() {
+		t.Error("Insert of an empty key should not change the tree")
+	}
+}
+
+func TestPersistentTreeStructuralSharing(t *testing.T) {
+	v1 := NewPersistentTree[byte, int]().
+		Insert([]byte("apple"), 1).
+		Insert([]byte("banana"), 2)
+	v2 := v1.Insert([]byte("apricot"), 3)
+
+	if v1.Root() == v2.Root() {
+		t.Error("Expected a new root after insert")
+	}
+	if childOf(v1.Root(), 'b') != childOf(v2.Root(), 'b') {
+		t.Error("Expected untouched subtree to be shared between versions")
+	}
+	if childOf(v1.Root(), 'a') == childOf(v2.Root(), 'a') {
+		t.Error("Expected modified path to be copied")
+	}
+	if childOf(v1.Root(), 'a').Text[len(childOf(v1.Root(), 'a').Text)-1] != 'e' {
+		t.Error("Old version was modified by the split")
+	}
+}
+
+func TestPersistentTreeTxn(t *testing.T) {
+	base := NewPersistentTree[byte, int]().Insert([]byte("a"), 1)
+	txn := base.Txn()
+	txn.Insert([]byte("ab"), 2)
+	txn.Insert([]byte("abc"), 3)
+	txn.Delete([]byte("a"))
+	if _, ok := txn.Get([]byte("abc")); !ok {
+		t.Error("Txn should see its own uncommitted inserts")
+	}
+	if _, ok := base.Get([]byte("ab")); ok {
+		t.Error("Base version should not see uncommitted inserts")
+	}
+
+	v1 := txn.Commit()
+	for _, key := range []string{"ab", "abc"} {
+		if _, ok := v1.Get([]byte(key)); !ok {
+			t.Errorf("Committed version is missing %q", key)
+		}
+	}
+	if _, ok := v1.Get([]byte("a")); ok {
+		t.Error("Committed version should not contain deleted key a")
+	}
+
+	// Mutations after commit must not leak into the committed version
+	txn.Insert([]byte("abd"), 4)
+	if _, ok := v1.Get([]byte("abd")); ok {
+		t.Error("Committed version was modified after commit")
+	}
+	if _, ok := txn.Commit().Get([]byte("abd")); !ok {
+		t.Error("Second commit is missing abd")
+	}
+}
+
+func TestPersistentTreeMatchesTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	randomKey := func() []byte {
+		key := make([]byte, 1+rng.Intn(6))
+		for i := range key {
+			key[i] = "abc"[rng.Intn(3)]
+		}
+		return key
+	}
+
+	tree := NewTree[byte, int]()
+	ptree := NewPersistentTree[byte, int]()
+	for i := 0; i < 500; i++ {
+		key := randomKey()
+		if rng.Intn(3) == 0 {
+			_, ok1 := tree.Delete(key)
+			var ok2 bool
+			ptree, _, ok2 = ptree.Delete(key)
+			if ok1 != ok2 {
+				t.Fatalf("Delete(%q) = %v, Tree reported %v", key, ok2, ok1)
+			}
+		} else {
+			tree.Insert(key, i)
+			ptree = ptree.Insert(key, i)
+		}
+
+		if tree.String() != ptree.String() {
+			t.Fatalf("Tree and PersistentTree differ after %d operations\n%s\n%s", i, tree.String(), ptree.String())
+		}
+		query := randomKey()
+		prefix1, val1, exact1 := tree.LongestCommonPrefixMatch(query)
+		prefix2, val2, exact2 := ptree.LongestCommonPrefixMatch(query)
+		if string(prefix1) != string(prefix2) || exact1 != exact2 || val1 != val2 && (val1 == nil || val2 == nil || *val1 != *val2) {
+			t.Fatalf("LCP(%q) differs from Tree after %d operations\n%s\n%s", query, i, tree.String(), ptree.String())
+		}
+	}
+}
+
+func TestPersistentTreeValues(t *testing.T) {
+	v := NewPersistentTree[byte, int]().
+		Insert([]byte("a"), 1).
+		Insert([]byte("abc"), 3).
+		Insert([]byte("ab"), 2)
+	// splitting below an End node keeps the node's own value
+	for key, expected := range map[string]int{"a": 1, "ab": 2, "abc": 3} {
+		if val, ok := v.Get([]byte(key)); !ok || *val != expected {
+			t.Errorf("Get(%q) = %v, %v, expected %d, true", key, val, ok, expected)
+		}
+	}
+
+	v = v.Insert([]byte("hello"), 4).Insert([]byte("help"), 5).Insert([]byte("helium"), 6)
+	v, _, _ = v.Delete([]byte("helium"))
+	// the intermediate node "hel" takes the most recent remaining value, not an arbitrary child's
+	if _, val, _ := v.LongestCommonPrefixMatch([]byte("helx")); val == nil || *val != 5 {
+		t.Errorf("LongestCommonPrefixMatch(helx) = %v, expected 5", val)
+	}
+	v, _, _ = v.Delete([]byte("help"))
+	// the intermediate node is merged into its only child
+	if got := childOf(v.Root(), 'h'); string(got.Text) != "hello" || !got.End {
+		t.Errorf("Expected hel to be merged into hello, got %q", got.Text)
+	}
+
+	// keys are copied, so reusing the caller's slice cannot change any version
+	key := []byte("xyz")
+	v = v.Insert(key, 7)
+	copy(key, "abc")
+	if _, ok := v.Get([]byte("xyz")); !ok {
+		t.Error("Modifying an inserted key changed the tree")
+	}
+}
+
+func TestPersistentTreeConcurrentReaders(t *testing.T) {
+	var current atomic.Pointer[PersistentTree[rune, int]]
+	current.Store(NewPersistentTree[rune, int]())
+
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				current.Load().LongestCommonPrefixMatch([]rune("hello-world"))
+			}
+		}()
+	}
+	keys := []string{"h", "he", "hello", "help", "hello-world", "world"}
+	for i, key := range keys {
+		current.Store(current.Load().Insert([]rune(key), i))
+	}
+	wg.Wait()
+
+	count := 0
+	current.Load().Walk(func(key []rune, val *int) bool {
+		count++
+		return true
+	})
+	if count != len(keys) {
+		t.Errorf("Expected %d keys, got %d", len(keys), count)
+	}
+}
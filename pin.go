@@ -0,0 +1,96 @@
+package lradix
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrPinned is returned by ConcurrentTree.RemoveNode for a node held by a Pin handle.
+var ErrPinned = errors.New("lradix: node is pinned")
+
+// PinHandle holds the nodes pinned by ConcurrentTree.Pin until it is passed to Unpin.
+type PinHandle[K comparable, T any] struct {
+	nodes    []*ConcurrentNode[K, T] // pinned nodes, from the root down
+	length   int
+	released atomic.Bool
+}
+
+// Length returns the length of the prefix of the key that was matched and pinned.
+func (h *PinHandle[K, T]) Length() int {
+	return h.length
+}
+
+// Pin holds every node along the path matched by the longest common prefix of str, including a
+// node matched only partially, so that the matched prefix stays in the tree: RemoveNode returns
+// ErrPinned for these nodes, Delete reports their keys as absent, and neither eviction nor
+// RemoveExpired removes them. Pinned intermediate nodes are not merged with their children either,
+// so they keep their identity until Unpin. Pins are reference counted, so a node stays pinned
+// until every handle holding it is released. Pinning counts as an access for eviction.
+func (t *ConcurrentTree[K, T]) Pin(str []K) *PinHandle[K, T] {
+	now := t.tick()
+	h := &PinHandle[K, T]{}
+	// pin counts are read by Validate while it holds t.mu.Lock
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	mark := t.Root
+	index := 0
+	for index < len(str) {
+		// the read lock on mark keeps next linked below it while next is pinned,
+		// and a pinned mark can no longer be removed or merged away
+		mark.RLock()
+		next, ok := mark.GetChild(str[index])
+		if !ok {
+			mark.RUnlock()
+			break
+		}
+		next.Lock()
+		next.pins++
+		t.touch(next, now)
+		t.trackLocked(next)
+		textLength := len(next.Text)
+		sharedPrefix := longestPrefix(next.Text, str[index:])
+		next.Unlock()
+		mark.RUnlock()
+		h.nodes = append(h.nodes, next)
+		index += sharedPrefix
+		if sharedPrefix < textLength {
+			// partial match, stop
+			break
+		}
+		mark = next
+	}
+	h.length = index
+	return h
+}
+
+// Unpin releases the nodes held by a handle returned by Pin. Nodes that are no longer pinned by
+// any handle get the cleanup that RemoveNode would have given them while they were pinned, and a
+// tree created with a capacity evicts the entries that the pin kept beyond it. Releasing a handle
+// more than once has no effect.
+func (t *ConcurrentTree[K, T]) Unpin(h *PinHandle[K, T]) {
+	if h == nil || !h.released.CompareAndSwap(false, true) {
+		return
+	}
+	t.mu.RLock()
+	// release from the deepest node up, so that a parent left without children by the
+	// cleanup of its child is still pinned and cleaned up in turn
+	for i := len(h.nodes) - 1; i >= 0; i-- {
+		node := h.nodes[i]
+		node.Lock()
+		node.pins--
+		t.trackLocked(node)
+		stale := node.pins == 0 && node.Parent != nil && !node.End && node.children.len() < 2
+		childless := node.children.len() == 0
+		node.Unlock()
+		switch {
+		case !stale:
+		case childless:
+			t.removeNode(node, nil)
+		default:
+			t.tidy(node)
+		}
+	}
+	removed := t.evict()
+	t.mu.RUnlock()
+	t.notifyEvicted(removed)
+}
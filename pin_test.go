@@ -0,0 +1,229 @@
+package lradix
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentTreePin(t *testing.T) {
+	tree := NewConcurrentTree[byte, int]()
+	tree.Insert([]byte("hello"), 1)
+	help := tree.Insert([]byte("help"), 2)
+	tree.Insert([]byte("world"), 3)
+
+	h := tree.Pin([]byte("helping"))
+	if h.Length() != 4 {
+		t.Errorf("Length() = %d, expected 4", h.Length())
+	}
+	if err := tree.RemoveNode(help); !errors.Is(err, ErrPinned) {
+		t.Errorf("RemoveNode of a pinned node = %v, expected ErrPinned", err)
+	}
+	if _, ok := tree.Delete([]byte("help")); ok {
+		t.Error("Delete removed a pinned key")
+	}
+	if val, ok := tree.Get([]byte("help")); !ok || *val != 2 {
+		t.Errorf("Get(help) = %v, %v while pinned, expected 2, true", val, ok)
+	}
+	// keys below the pinned path are not pinned
+	if _, ok := tree.Delete([]byte("hello")); !ok {
+		t.Error("Delete(hello) failed although hello is not pinned")
+	}
+
+	// a partially matched node is pinned as well
+	w := tree.Pin([]byte("wor"))
+	if w.Length() != 3 {
+		t.Errorf("Length() = %d, expected 3", w.Length())
+	}
+	if _, ok := tree.Delete([]byte("world")); ok {
+		t.Error("Delete removed a partially matched pinned key")
+	}
+
+	tree.Unpin(h)
+	tree.Unpin(w)
+	tree.Unpin(w)
+	if _, ok := tree.Delete([]byte("help")); !ok {
+		t.Error("Delete(help) failed after Unpin")
+	}
+	if err := tree.RemoveNode(tree.Insert([]byte("world"), 4)); err != nil {
+		t.Errorf("RemoveNode after Unpin = %v, expected nil", err)
+	}
+	if tree.Len() != 0 {
+		t.Errorf("Len() = %d, expected 0", tree.Len())
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentTreeTryDeletePinned(t *testing.T) {
+	tree := NewConcurrentTree[byte, int]()
+	tree.Insert([]byte("help"), 2)
+	h := tree.Pin([]byte("help"))
+
+	if val, ok, err := tree.TryDelete([]byte("help")); ok || val != nil || !errors.Is(err, ErrPinned) {
+		t.Errorf("TryDelete(help) = %v, %v, %v while pinned, expected nil, false, ErrPinned", val, ok, err)
+	}
+	if val, ok, err := tree.TryDelete([]byte("hello")); ok || val != nil || err != nil {
+		t.Errorf("TryDelete(hello) = %v, %v, %v for a missing key, expected nil, false, nil", val, ok, err)
+	}
+	same := func(a, b int) bool { return a == b }
+	if ok, err := tree.TryCompareAndDelete([]byte("help"), 2, same); ok || !errors.Is(err, ErrPinned) {
+		t.Errorf("TryCompareAndDelete(help, 2) = %v, %v while pinned, expected false, ErrPinned", ok, err)
+	}
+	if ok, err := tree.TryCompareAndDelete([]byte("help"), 3, same); ok || err != nil {
+		t.Errorf("TryCompareAndDelete(help, 3) = %v, %v for another value, expected false, nil", ok, err)
+	}
+	// the boolean forms report pinned keys like missing ones
+	if _, ok := tree.Delete([]byte("help")); ok {
+		t.Error("Delete removed a pinned key")
+	}
+	if tree.CompareAndDelete([]byte("help"), 2, same) {
+		t.Error("CompareAndDelete removed a pinned key")
+	}
+
+	tree.Unpin(h)
+	if val, ok, err := tree.TryDelete([]byte("help")); !ok || *val != 2 || err != nil {
+		t.Errorf("TryDelete(help) = %v, %v, %v after Unpin, expected 2, true, nil", val, ok, err)
+	}
+}
+
+func TestConcurrentTreePinRefCount(t *testing.T) {
+	tree := NewConcurrentTree[byte, int]()
+	tree.Insert([]byte("abc"), 1)
+	first := tree.Pin([]byte("abc"))
+	second := tree.Pin([]byte("ab"))
+	tree.Unpin(first)
+	tree.Unpin(first)
+	if _, ok := tree.Delete([]byte("abc")); ok {
+		t.Error("Delete removed a key still pinned by another handle")
+	}
+	tree.Unpin(second)
+	if _, ok := tree.Delete([]byte("abc")); !ok {
+		t.Error("Delete failed after every handle was released")
+	}
+}
+
+func TestConcurrentTreePinIntermediate(t *testing.T) {
+	tree := NewConcurrentTree[byte, int]()
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2)
+	tree.Insert([]byte("hero"), 3)
+	h := tree.Pin([]byte("hel"))
+
+	// the pinned intermediate node "l" is neither merged nor removed with its children
+	tree.Delete([]byte("hello"))
+	tree.Delete([]byte("help"))
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate with a pinned intermediate node: %v", err)
+	}
+	if _, length, _, _ := tree.LongestCommonPrefixLength([]byte("hel")); length != 3 {
+		t.Errorf("LongestCommonPrefixLength(hel) = %d while pinned, expected 3", length)
+	}
+
+	tree.Unpin(h)
+	if err := tree.Validate(); err != nil {
+		t.Errorf("Validate after Unpin: %v", err)
+	}
+	if keys := tree.KeysWithPrefix(nil); len(keys) != 1 || string(keys[0]) != "hero" {
+		t.Errorf("KeysWithPrefix() = %q, expected [hero]", keys)
+	}
+	if got := tree.Stats().Nodes; got != 1 {
+		t.Errorf("Stats().Nodes = %d after Unpin, expected the path to be compacted into 1 node", got)
+	}
+}
+
+func TestConcurrentTreePinEviction(t *testing.T) {
+	var evictedKeys []string
+	tree := NewConcurrentTree[byte, int](WithMaxKeys(2), WithEvictCallback(func(key []byte, _ *int) {
+		evictedKeys = append(evictedKeys, string(key))
+	}))
+	tree.Insert([]byte("a"), 1)
+	tree.Insert([]byte("b"), 2)
+	h := tree.Pin([]byte("a"))
+	tree.LongestCommonPrefixMatch([]byte("b"))
+	tree.Insert([]byte("c"), 3) // a is the least recently used, but pinned
+	if !slices.Equal(evictedKeys, []string{"b"}) {
+		t.Errorf("Evicted %q, expected [b]", evictedKeys)
+	}
+	tree.Unpin(h)
+
+	// a pin can keep the tree beyond its capacity until it is released
+	tree = NewConcurrentTree[byte, int](WithMaxKeys(1), WithEvictCallback(func(key []byte, _ *int) {
+		evictedKeys = append(evictedKeys, string(key))
+	}))
+	evictedKeys = nil
+	tree.Insert([]byte("abcd"), 1)
+	h = tree.Pin([]byte("abc"))
+	tree.Insert([]byte("abc"), 2)
+	if len(evictedKeys) != 0 || tree.Len() != 2 {
+		t.Errorf("Evicted %q with %d keys left, expected the pinned leaf to be kept", evictedKeys, tree.Len())
+	}
+	tree.Unpin(h)
+	if !slices.Equal(evictedKeys, []string{"abcd"}) || tree.Len() != 1 {
+		t.Errorf("Evicted %q with %d keys left after Unpin, expected [abcd] and 1 key", evictedKeys, tree.Len())
+	}
+}
+
+func TestConcurrentTreePinConcurrent(t *testing.T) {
+	tree := NewConcurrentTree[byte, int](WithMaxKeys(30))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			var pins []*PinHandle[byte, int]
+			for i := 0; i < 500; i++ {
+				key := []byte(randomKey(r))
+				switch r.Intn(5) {
+				case 0:
+					tree.Delete(key)
+				case 1:
+					pins = append(pins, tree.Pin(key))
+				case 3:
+					// Validate reads pin counts while other goroutines pin and unpin
+					if err := tree.Validate(); err != nil {
+						t.Error(err)
+					}
+				case 2:
+					if len(pins) > 0 {
+						j := r.Intn(len(pins))
+						tree.Unpin(pins[j])
+						pins = slices.Delete(pins, j, j+1)
+					}
+				default:
+					tree.Insert(key, i)
+				}
+			}
+			for _, h := range pins {
+				tree.Unpin(h)
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+
+	var pinned []string
+	var visit func(node *ConcurrentNode[byte, int])
+	visit = func(node *ConcurrentNode[byte, int]) {
+		if node.pins != 0 {
+			pinned = append(pinned, string(node.Text))
+		}
+		node.ForEachChild(func(_ byte, child *ConcurrentNode[byte, int]) bool {
+			visit(child)
+			return true
+		})
+	}
+	visit(tree.Root)
+	if len(pinned) != 0 {
+		t.Errorf("Nodes %q are still pinned after every handle was released", pinned)
+	}
+	if tree.Len() > 30 {
+		t.Errorf("Len() = %d, expected at most 30 once unpinned", tree.Len())
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
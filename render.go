@@ -0,0 +1,244 @@
+package lradix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RenderOptions controls the graph output of WriteDOT and WriteMermaid.
+// The zero value renders the whole tree with values formatted by %v.
+type RenderOptions[K comparable, T any] struct {
+	MaxDepth    int                 // Maximum depth below the root to render, 0 for no limit
+	FormatValue func(val *T) string // Formats node values, nil values included; defaults to %v and nil
+	ShowIDs     bool                // Include node IDs in labels (ConcurrentTree only)
+	Highlight   []K                 // Query whose LongestCommonPrefixMatch path is highlighted, if not nil
+}
+
+// renderNode is the tree-independent form of a node handed to the graph writers.
+type renderNode struct {
+	id          int64 // node ID, only set for concurrent nodes
+	text        string
+	val         string
+	end         bool
+	highlighted bool // on the path taken by the highlight query
+	truncated   bool // children omitted because of MaxDepth
+	children    []*renderNode
+}
+
+// renderState carries the per-node state of the highlight query while collecting nodes.
+type renderState[K comparable] struct {
+	depth     int
+	onPath    bool // node is on the highlighted path
+	remaining []K  // part of the highlight query left after this node's text
+	matched   bool // node's text was matched completely, so the path may continue below it
+}
+
+// child returns the state of a child with the given text.
+func (s renderState[K]) child(text []K) renderState[K] {
+	next := renderState[K]{depth: s.depth + 1}
+	if s.onPath && s.matched && len(s.remaining) > 0 && len(text) > 0 && text[0] == s.remaining[0] {
+		shared := longestPrefix(text, s.remaining)
+		next.onPath = true
+		next.remaining = s.remaining[shared:]
+		next.matched = shared == len(text)
+	}
+	return next
+}
+
+func newRenderState[K comparable, T any](opts RenderOptions[K, T]) renderState[K] {
+	return renderState[K]{onPath: opts.Highlight != nil, remaining: opts.Highlight, matched: true}
+}
+
+func (opts RenderOptions[K, T]) formatValue(val *T) string {
+	if opts.FormatValue != nil {
+		return opts.FormatValue(val)
+	}
+	return displayValue(val)
+}
+
+// WriteDOT writes the tree as a Graphviz DOT digraph to w.
+func (t *Tree[K, T]) WriteDOT(w io.Writer, opts RenderOptions[K, T]) error {
+	return writeDOT(w, collectRenderNode(t.Root, opts, newRenderState(opts)), false)
+}
+
+// WriteMermaid writes the tree as a Mermaid flowchart to w.
+func (t *Tree[K, T]) WriteMermaid(w io.Writer, opts RenderOptions[K, T]) error {
+	return writeMermaid(w, collectRenderNode(t.Root, opts, newRenderState(opts)), false)
+}
+
+// WriteDOT writes the tree as a Graphviz DOT digraph to w.
+// Writers are paused while the nodes are collected, as in Snapshot, so the graph is consistent.
+func (t *ConcurrentTree[K, T]) WriteDOT(w io.Writer, opts RenderOptions[K, T]) error {
+	return writeDOT(w, t.collectRenderNodes(opts), opts.ShowIDs)
+}
+
+// WriteMermaid writes the tree as a Mermaid flowchart to w.
+// Writers are paused while the nodes are collected, as in Snapshot, so the graph is consistent.
+func (t *ConcurrentTree[K, T]) WriteMermaid(w io.Writer, opts RenderOptions[K, T]) error {
+	return writeMermaid(w, t.collectRenderNodes(opts), opts.ShowIDs)
+}
+
+func (t *ConcurrentTree[K, T]) collectRenderNodes(opts RenderOptions[K, T]) *renderNode {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return collectConcurrentRenderNode(t.Root, opts, newRenderState(opts))
+}
+
+// collectRenderNode recursively converts a node and its children, in stable order, for rendering.
+func collectRenderNode[K comparable, T any](node *Node[K, T], opts RenderOptions[K, T], state renderState[K]) *renderNode {
+	rn := &renderNode{
+		text:        displayText(node.Text),
+		val:         opts.formatValue(node.Val),
+		end:         node.End,
+		highlighted: state.onPath,
+	}
+	if opts.MaxDepth > 0 && state.depth >= opts.MaxDepth {
+		rn.truncated = node.children.len() > 0
+		return rn
+	}
+	eachChild(&node.children, compareStable[K], func(child *Node[K, T]) bool {
+		rn.children = append(rn.children, collectRenderNode(child, opts, state.child(child.Text)))
+		return true
+	})
+	return rn
+}
+
+// collectConcurrentRenderNode recursively converts a concurrent node and its children, in stable order,
+// for rendering. The caller must hold t.mu.Lock, so no node can be modified during the walk.
+func collectConcurrentRenderNode[K comparable, T any](node *ConcurrentNode[K, T], opts RenderOptions[K, T], state renderState[K]) *renderNode {
+	rn := &renderNode{
+		id:          node.ID,
+		text:        displayText(node.Text),
+		val:         opts.formatValue(node.Val),
+		end:         node.End,
+		highlighted: state.onPath,
+	}
+	if opts.MaxDepth > 0 && state.depth >= opts.MaxDepth {
+		rn.truncated = node.children.len() > 0
+		return rn
+	}
+	eachChild(&node.children, compareStable[K], func(child *ConcurrentNode[K, T]) bool {
+		rn.children = append(rn.children, collectConcurrentRenderNode(child, opts, state.child(child.Text)))
+		return true
+	})
+	return rn
+}
+
+// label returns the display label of a node, one line per entry.
+func (rn *renderNode) label(showID bool) []string {
+	lines := []string{rn.text, "val: " + rn.val}
+	if showID {
+		lines = append(lines, fmt.Sprintf("id: %d", rn.id))
+	}
+	return lines
+}
+
+// writeDOT writes the collected nodes as a DOT digraph. End nodes are drawn with a double border,
+// highlighted nodes and edges in red, and truncated subtrees as a dashed ellipsis node.
+func writeDOT(w io.Writer, root *renderNode, showID bool) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph lradix {\n")
+	bw.WriteString("  node [shape=box];\n")
+	seq := 0
+	var visit func(rn *renderNode) string
+	visit = func(rn *renderNode) string {
+		name := fmt.Sprintf("n%d", seq)
+		seq++
+		lines := rn.label(showID)
+		for i, line := range lines {
+			lines[i] = escapeDOT(line)
+		}
+		attrs := []string{`label="` + strings.Join(lines, `\n`) + `"`}
+		if rn.end {
+			attrs = append(attrs, "peripheries=2")
+		}
+		if rn.highlighted {
+			attrs = append(attrs, "color=red", "penwidth=2")
+		}
+		fmt.Fprintf(bw, "  %s [%s];\n", name, strings.Join(attrs, ", "))
+		if rn.truncated {
+			more := fmt.Sprintf("n%d", seq)
+			seq++
+			fmt.Fprintf(bw, "  %s [label=\"...\", style=dashed];\n", more)
+			fmt.Fprintf(bw, "  %s -> %s [style=dashed];\n", name, more)
+		}
+		for _, child := range rn.children {
+			childName := visit(child)
+			if child.highlighted {
+				fmt.Fprintf(bw, "  %s -> %s [color=red, penwidth=2];\n", name, childName)
+			} else {
+				fmt.Fprintf(bw, "  %s -> %s;\n", name, childName)
+			}
+		}
+		return name
+	}
+	visit(root)
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// writeMermaid writes the collected nodes as a Mermaid flowchart. End nodes are drawn as
+// subroutine boxes, highlighted nodes and links in red, and truncated subtrees as an ellipsis node.
+func writeMermaid(w io.Writer, root *renderNode, showID bool) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("graph TD\n")
+	seq := 0
+	links := 0
+	highlightedNodes := []string{}
+	highlightedLinks := []string{}
+	var visit func(rn *renderNode) string
+	visit = func(rn *renderNode) string {
+		name := fmt.Sprintf("n%d", seq)
+		seq++
+		lines := rn.label(showID)
+		for i, line := range lines {
+			lines[i] = escapeMermaid(line)
+		}
+		label := `"` + strings.Join(lines, "<br/>") + `"`
+		if rn.end {
+			fmt.Fprintf(bw, "  %s[[%s]]\n", name, label)
+		} else {
+			fmt.Fprintf(bw, "  %s[%s]\n", name, label)
+		}
+		if rn.highlighted {
+			highlightedNodes = append(highlightedNodes, name)
+		}
+		if rn.truncated {
+			more := fmt.Sprintf("n%d", seq)
+			seq++
+			fmt.Fprintf(bw, "  %s[\"...\"]\n", more)
+			fmt.Fprintf(bw, "  %s -.-> %s\n", name, more)
+			links++
+		}
+		for _, child := range rn.children {
+			childName := visit(child)
+			fmt.Fprintf(bw, "  %s --> %s\n", name, childName)
+			if child.highlighted {
+				highlightedLinks = append(highlightedLinks, fmt.Sprintf("%d", links))
+			}
+			links++
+		}
+		return name
+	}
+	visit(root)
+	if len(highlightedNodes) > 0 {
+		bw.WriteString("  classDef highlight stroke:#f00,stroke-width:3px;\n")
+		fmt.Fprintf(bw, "  class %s highlight\n", strings.Join(highlightedNodes, ","))
+	}
+	if len(highlightedLinks) > 0 {
+		fmt.Fprintf(bw, "  linkStyle %s stroke:#f00,stroke-width:3px\n", strings.Join(highlightedLinks, ","))
+	}
+	return bw.Flush()
+}
+
+// escapeDOT escapes a label line for use inside a double-quoted DOT string.
+func escapeDOT(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "", "\t", " ").Replace(s)
+}
+
+// escapeMermaid escapes a label line for use inside a double-quoted Mermaid label.
+func escapeMermaid(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "\n", "<br/>", "\r", "", "\t", " ").Replace(s)
+}
@@ -0,0 +1,95 @@
+package lradix
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWriteDOT(t *testing.T) {
+	tree := NewTree[byte, int]()
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2)
+	tree.Insert([]byte("world"), 3)
+
+	var b bytes.Buffer
+	err := tree.WriteDOT(&b, RenderOptions[byte, int]{Highlight: []byte("help!")})
+	if err != nil {
+		t.Fatalf("WriteDOT() error = %v", err)
+	}
+	expected := `digraph lradix {
+  node [shape=box];
+  n0 [label="ROOT\nval: nil", color=red, penwidth=2];
+  n1 [label="hel\nval: 2", color=red, penwidth=2];
+  n2 [label="lo\nval: 1", peripheries=2];
+  n1 -> n2;
+  n3 [label="p\nval: 2", peripheries=2, color=red, penwidth=2];
+  n1 -> n3 [color=red, penwidth=2];
+  n0 -> n1 [color=red, penwidth=2];
+  n4 [label="world\nval: 3", peripheries=2];
+  n0 -> n4;
+}
+`
+	if b.String() != expected {
+		t.Errorf("WriteDOT() =\n%s\nexpected\n%s", b.String(), expected)
+	}
+}
+
+func TestWriteDOTOptions(t *testing.T) {
+	tree := NewTree[byte, string]()
+	tree.Insert([]byte("a\"b"), "x")
+	tree.Insert([]byte("a\"bc"), "y")
+
+	var b bytes.Buffer
+	tree.WriteDOT(&b, RenderOptions[byte, string]{
+		MaxDepth: 1,
+		FormatValue: func(val *string) string {
+			if val == nil {
+				return "-"
+			}
+			return strings.ToUpper(*val)
+		},
+	})
+	out := b.String()
+	for _, want := range []string{`label="a\"b\nval: X"`, `label="ROOT\nval: -"`, `label="...", style=dashed`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteDOT() =\n%s\nmissing %s", out, want)
+		}
+	}
+	if strings.Contains(out, `label="c`) {
+		t.Errorf("WriteDOT() rendered nodes below MaxDepth:\n%s", out)
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	tree := NewConcurrentTree[rune, int]()
+	tree.Insert([]rune("hello"), 1)
+	node := tree.Insert([]rune("help"), 2)
+
+	var b bytes.Buffer
+	err := tree.WriteMermaid(&b, RenderOptions[rune, int]{ShowIDs: true, Highlight: []rune("hex")})
+	if err != nil {
+		t.Fatalf("WriteMermaid() error = %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"graph TD\n",
+		`n0["ROOT<br/>val: nil<br/>id: `,
+		`n1["hel<br/>val: 2<br/>id: `,
+		fmt.Sprintf(`n3[["p<br/>val: 2<br/>id: %d"]]`, node.ID),
+		"n0 --> n1\n",
+		"class n0,n1 highlight\n", // the query stops inside "hel"
+		"linkStyle 2 stroke",      // n0 --> n1 is the last link written
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteMermaid() =\n%s\nmissing %q", out, want)
+		}
+	}
+
+	b.Reset()
+	tree.WriteDOT(&b, RenderOptions[rune, int]{})
+	if strings.Contains(b.String(), "id: ") || strings.Contains(b.String(), "color=red") {
+		t.Errorf("WriteDOT() with default options =\n%s", b.String())
+	}
+}
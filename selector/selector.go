@@ -0,0 +1,246 @@
+// Package selector picks a backend from the candidates returned by
+// ConcurrentTree.MultiLongestCommonPrefixMatch, trading cache reuse against backend load.
+package selector
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	lradix "github.com/homily707/go-lcp-radix"
+)
+
+// ErrNoCandidates is returned by Select when no candidate carries a value.
+var ErrNoCandidates = errors.New("selector: no candidates with a value")
+
+// LoadReporter returns the current load of the backend stored as a value in the tree,
+// such as its number of in-flight requests. Loads must not be negative; lower is better.
+type LoadReporter[T any] func(val *T) float64
+
+// Identity returns a comparable key identifying the backend stored as a value in the tree,
+// such as its address. The tree stores a separate copy of the value for every key inserted,
+// so candidates are matched to backends by identity rather than by value pointer.
+type Identity[T any] func(val *T) any
+
+// Policy decides how Select weighs match ratio against load.
+type Policy int
+
+const (
+	// PolicyCacheAware picks the candidate with the highest match ratio if it reaches the
+	// threshold, and the least loaded candidate otherwise. This is the default policy.
+	PolicyCacheAware Policy = iota
+	// PolicyWeightedRandom picks a candidate at random with probability proportional to
+	// (1 + MatchLength) / (1 + load), favouring long matches on lightly loaded backends.
+	PolicyWeightedRandom
+	// PolicyPowerOfTwo picks two candidates at random and keeps the less loaded one,
+	// breaking ties by match ratio.
+	PolicyPowerOfTwo
+)
+
+func (p Policy) String() string {
+	switch p {
+	case PolicyCacheAware:
+		return "CacheAware"
+	case PolicyWeightedRandom:
+		return "WeightedRandom"
+	case PolicyPowerOfTwo:
+		return "PowerOfTwo"
+	}
+	return fmt.Sprintf("Policy(%d)", int(p))
+}
+
+// Option configures a Selector created by New.
+type Option func(*config)
+
+type config struct {
+	policy    Policy
+	threshold float64
+	source    rand.Source
+}
+
+// WithPolicy selects the policy used by Select.
+func WithPolicy(policy Policy) Option {
+	return func(c *config) {
+		c.policy = policy
+	}
+}
+
+// WithThreshold sets the match ratio, between 0 and 1, from which PolicyCacheAware prefers
+// the best match over the least loaded candidate. The default is 0.5.
+func WithThreshold(threshold float64) Option {
+	return func(c *config) {
+		c.threshold = threshold
+	}
+}
+
+// WithSource sets the source of randomness of the random policies, so that tests can
+// make their decisions deterministic. The default source is seeded with the current time.
+func WithSource(source rand.Source) Option {
+	return func(c *config) {
+		c.source = source
+	}
+}
+
+// Selector picks backends for queries. It is safe for concurrent use.
+type Selector[K comparable, T any] struct {
+	load      LoadReporter[T]
+	identity  Identity[T]
+	policy    Policy
+	threshold float64
+
+	mu   sync.Mutex // guards rand
+	rand *rand.Rand
+}
+
+// New creates a Selector that asks load for the load of every candidate backend, and tells
+// candidates of the same backend apart from others with identity.
+func New[K comparable, T any](load LoadReporter[T], identity Identity[T], opts ...Option) *Selector[K, T] {
+	c := config{threshold: 0.5}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.source == nil {
+		c.source = rand.NewSource(time.Now().UnixNano())
+	}
+	return &Selector[K, T]{
+		load:      load,
+		identity:  identity,
+		policy:    c.policy,
+		threshold: c.threshold,
+		rand:      rand.New(c.source),
+	}
+}
+
+// Score describes a candidate backend as seen by Select.
+type Score struct {
+	ID          int64   // ID of the node holding the candidate with the longest match for the backend
+	MatchLength int     // length of the longest match of the backend
+	MatchRatio  float64 // MatchLength divided by the length of the query
+	Load        float64 // load reported for the backend
+	Weight      float64 // selection weight, only set by PolicyWeightedRandom
+}
+
+// Explanation describes how a decision was made, for logging.
+type Explanation struct {
+	Policy    Policy
+	Threshold float64 // threshold of PolicyCacheAware
+	Reason    string  // short description of why the backend was chosen
+	Chosen    Score   // score of the chosen backend
+	Scores    []Score // scores of every backend considered, in candidate order
+}
+
+// Decision is the backend picked by Select.
+type Decision[T any] struct {
+	Match       lradix.Match[T] // candidate of the chosen backend with its longest match
+	Explanation Explanation
+}
+
+// Select picks a backend for query among candidates, typically the result of
+// MultiLongestCommonPrefixMatch(query). Candidates are identified by the Identity of their
+// value: those without a value are ignored, and a backend reported several times, for example
+// under several keys, is scored by its longest match. It returns ErrNoCandidates if no candidate carries a value.
+func (s *Selector[K, T]) Select(query []K, candidates []lradix.Match[T]) (Decision[T], error) {
+	var backends []lradix.Match[T]
+	index := map[any]int{}
+	for _, c := range candidates {
+		if c.Value == nil {
+			continue
+		}
+		id := s.identity(c.Value)
+		if i, ok := index[id]; ok {
+			if c.MatchLength > backends[i].MatchLength {
+				backends[i] = c
+			}
+			continue
+		}
+		index[id] = len(backends)
+		backends = append(backends, c)
+	}
+	if len(backends) == 0 {
+		return Decision[T]{}, ErrNoCandidates
+	}
+
+	scores := make([]Score, len(backends))
+	for i, b := range backends {
+		scores[i] = Score{ID: b.ID, MatchLength: b.MatchLength, Load: s.load(b.Value)}
+		if len(query) > 0 {
+			scores[i].MatchRatio = float64(b.MatchLength) / float64(len(query))
+		}
+	}
+	e := Explanation{Policy: s.policy, Threshold: s.threshold, Scores: scores}
+	var chosen int
+	switch s.policy {
+	case PolicyWeightedRandom:
+		chosen, e.Reason = s.weightedRandom(scores)
+	case PolicyPowerOfTwo:
+		chosen, e.Reason = s.powerOfTwo(scores)
+	default:
+		chosen, e.Reason = s.cacheAware(scores)
+	}
+	e.Chosen = scores[chosen]
+	return Decision[T]{Match: backends[chosen], Explanation: e}, nil
+}
+
+// cacheAware implements PolicyCacheAware.
+func (s *Selector[K, T]) cacheAware(scores []Score) (int, string) {
+	best := 0
+	for i, sc := range scores {
+		if sc.MatchRatio > scores[best].MatchRatio ||
+			sc.MatchRatio == scores[best].MatchRatio && sc.Load < scores[best].Load {
+			best = i
+		}
+	}
+	if scores[best].MatchRatio >= s.threshold {
+		return best, fmt.Sprintf("match ratio %.2f reaches threshold %.2f", scores[best].MatchRatio, s.threshold)
+	}
+	least := 0
+	for i, sc := range scores {
+		if sc.Load < scores[least].Load ||
+			sc.Load == scores[least].Load && sc.MatchRatio > scores[least].MatchRatio {
+			least = i
+		}
+	}
+	return least, fmt.Sprintf("best match ratio %.2f below threshold %.2f, least loaded", scores[best].MatchRatio, s.threshold)
+}
+
+// weightedRandom implements PolicyWeightedRandom.
+func (s *Selector[K, T]) weightedRandom(scores []Score) (int, string) {
+	total := 0.0
+	for i := range scores {
+		scores[i].Weight = float64(1+scores[i].MatchLength) / (1 + scores[i].Load)
+		total += scores[i].Weight
+	}
+	s.mu.Lock()
+	r := s.rand.Float64() * total
+	s.mu.Unlock()
+	chosen := len(scores) - 1
+	for i, sc := range scores {
+		if r < sc.Weight {
+			chosen = i
+			break
+		}
+		r -= sc.Weight
+	}
+	return chosen, fmt.Sprintf("drawn with probability %.2f", scores[chosen].Weight/total)
+}
+
+// powerOfTwo implements PolicyPowerOfTwo.
+func (s *Selector[K, T]) powerOfTwo(scores []Score) (int, string) {
+	if len(scores) == 1 {
+		return 0, "only candidate"
+	}
+	s.mu.Lock()
+	a := s.rand.Intn(len(scores))
+	b := s.rand.Intn(len(scores) - 1)
+	s.mu.Unlock()
+	if b >= a {
+		b++
+	}
+	if scores[b].Load < scores[a].Load ||
+		scores[b].Load == scores[a].Load && scores[b].MatchRatio > scores[a].MatchRatio {
+		a, b = b, a
+	}
+	return a, fmt.Sprintf("less loaded of two random choices, other load %.2f", scores[b].Load)
+}
@@ -0,0 +1,162 @@
+package selector
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	lradix "github.com/homily707/go-lcp-radix"
+)
+
+type backend struct {
+	name     string
+	inflight float64
+}
+
+func backendLoad(b *backend) float64 {
+	return b.inflight
+}
+
+func backendName(b *backend) any {
+	return b.name
+}
+
+// newRoutingTree stores the prefixes cached by three backends.
+func newRoutingTree() *lradix.ConcurrentTree[byte, backend] {
+	tree := lradix.NewConcurrentTree[byte, backend]()
+	tree.Insert([]byte("system prompt: you are helpful"), backend{"a", 8})
+	tree.Insert([]byte("system prompt: you are terse"), backend{"b", 1})
+	tree.Insert([]byte("translate to french"), backend{"c", 0})
+	return tree
+}
+
+func TestSelectCacheAware(t *testing.T) {
+	tree := newRoutingTree()
+	s := New[byte](backendLoad, backendName, WithThreshold(0.5))
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		// a long match on a busy backend beats an idle one
+		{"system prompt: you are helpful, answer", "a"},
+		// a short shared prefix falls back to the least loaded backend among the candidates
+		{"system prompt: be brief and answer the question in detail please", "b"},
+		{"unrelated", "c"},
+	}
+	for _, tc := range testCases {
+		query := []byte(tc.query)
+		d, err := s.Select(query, tree.MultiLongestCommonPrefixMatch(query))
+		if err != nil {
+			t.Fatalf("Select(%q) failed: %v", tc.query, err)
+		}
+		if d.Match.Value.name != tc.expected {
+			t.Errorf("Select(%q) = %s (%s), expected %s", tc.query, d.Match.Value.name, d.Explanation.Reason, tc.expected)
+		}
+		if d.Explanation.Policy != PolicyCacheAware || d.Explanation.Chosen.MatchLength != d.Match.MatchLength {
+			t.Errorf("Select(%q) explained as %+v, inconsistent with %+v", tc.query, d.Explanation, d.Match)
+		}
+	}
+}
+
+func TestSelectDeduplicatesBackends(t *testing.T) {
+	a, b := &backend{"a", 0}, &backend{"b", 0}
+	candidates := []lradix.Match[backend]{
+		lradix.NewMatch(1, 2, a, false),
+		lradix.NewMatch(2, 6, a, false),
+		lradix.NewMatch(3, 4, b, false),
+		lradix.NewMatch[backend](4, 4, nil, false),
+	}
+	d, err := New[byte](backendLoad, backendName).Select([]byte("abcdefgh"), candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Explanation.Scores) != 2 {
+		t.Fatalf("Scored %+v, expected one score per backend", d.Explanation.Scores)
+	}
+	if d.Match.ID != 2 || d.Explanation.Chosen.MatchRatio != 0.75 {
+		t.Errorf("Select chose %+v with %+v, expected node 2 with ratio 0.75", d.Match, d.Explanation.Chosen)
+	}
+
+	if _, err := New[byte](backendLoad, backendName).Select([]byte("x"), candidates[3:]); !errors.Is(err, ErrNoCandidates) {
+		t.Errorf("Select without values = %v, expected ErrNoCandidates", err)
+	}
+}
+
+func TestSelectDeduplicatesTreeValues(t *testing.T) {
+	// every key stores its own copy of the backend
+	tree := lradix.NewConcurrentTree[byte, backend]()
+	tree.Insert([]byte("sys"), backend{"a", 0})
+	tree.Insert([]byte("system"), backend{"a", 0})
+	tree.Insert([]byte("system prompt"), backend{"a", 0})
+	tree.Insert([]byte("sysadmin"), backend{"b", 0}) // off the path of the query
+	query := []byte("system prompt: hi")
+	candidates := tree.MultiLongestCommonPrefixMatch(query)
+	if len(candidates) < 3 {
+		t.Fatalf("MultiLongestCommonPrefixMatch(%q) = %+v, expected a candidate per key of a", query, candidates)
+	}
+
+	d, err := New[byte](backendLoad, backendName).Select(query, candidates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Explanation.Scores) != 1 {
+		t.Fatalf("Scored %+v, expected a single score for a", d.Explanation.Scores)
+	}
+	if d.Match.Value.name != "a" || d.Match.MatchLength != len("system prompt") {
+		t.Errorf("Select chose %s with match length %d, expected a with %d", d.Match.Value.name, d.Match.MatchLength, len("system prompt"))
+	}
+}
+
+func TestSelectWeightedRandom(t *testing.T) {
+	long, short := &backend{"long", 0}, &backend{"short", 0}
+	candidates := []lradix.Match[backend]{
+		lradix.NewMatch(1, 9, long, false),
+		lradix.NewMatch(2, 0, short, false),
+	}
+	s := New[byte](backendLoad, backendName, WithPolicy(PolicyWeightedRandom), WithSource(rand.NewSource(1)))
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		d, err := s.Select([]byte("0123456789"), candidates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[d.Match.Value.name]++
+	}
+	// weights are 10 and 1
+	if counts["long"] < 850 || counts["short"] < 40 {
+		t.Errorf("Picked %v, expected roughly 10 to 1", counts)
+	}
+
+	// load lowers the weight of the long match below the short one
+	long.inflight = 19
+	d, _ := s.Select([]byte("0123456789"), candidates)
+	if w := d.Explanation.Scores[0].Weight; w != 0.5 {
+		t.Errorf("Weight = %v, expected (1+9)/(1+19) = 0.5", w)
+	}
+}
+
+func TestSelectPowerOfTwo(t *testing.T) {
+	backends := []*backend{{"a", 3}, {"b", 1}, {"c", 2}}
+	var candidates []lradix.Match[backend]
+	for i, b := range backends {
+		candidates = append(candidates, lradix.NewMatch(int64(i), 0, b, false))
+	}
+	s := New[byte](backendLoad, backendName, WithPolicy(PolicyPowerOfTwo), WithSource(rand.NewSource(1)))
+	counts := map[string]int{}
+	for i := 0; i < 300; i++ {
+		d, err := s.Select([]byte("q"), candidates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[d.Match.Value.name]++
+	}
+	// the most loaded backend always loses its pair, the least loaded one always wins
+	if counts["a"] != 0 || counts["b"] < counts["c"] {
+		t.Errorf("Picked %v, expected b most often and never a", counts)
+	}
+
+	d, _ := s.Select([]byte("q"), candidates[:1])
+	if d.Match.Value.name != "a" {
+		t.Errorf("Select with a single candidate = %s, expected a", d.Match.Value.name)
+	}
+}
@@ -0,0 +1,139 @@
+package lradix
+
+import "reflect"
+
+// Stats describes the shape and approximate memory footprint of a tree.
+// The root node is not counted in any of the fields.
+type Stats struct {
+	Keys              int         // Number of stored keys
+	Nodes             int         // Total number of nodes
+	IntermediateNodes int         // Nodes that do not represent the end of a complete key
+	EndNodes          int         // Nodes that represent the end of a complete key
+	MaxDepth          int         // Depth of the deepest node, children of the root have depth 1
+	AvgDepth          float64     // Average depth of the End nodes
+	Fanout            map[int]int // Number of nodes by their number of children
+	TextElements      int         // Total key elements held in node text fragments
+	EstimatedBytes    int64       // Estimated heap footprint of nodes, child storage, texts and values
+}
+
+// statsCollector accumulates Stats over a tree walk.
+type statsCollector struct {
+	stats      Stats
+	depthSum   int
+	nodeSize   int64
+	keySize    int64
+	valSize    int64
+	seenValues map[any]struct{} // value pointers already accounted for, shared between nodes
+}
+
+func newStatsCollector[K comparable, T any](nodeSize uintptr) *statsCollector {
+	return &statsCollector{
+		stats:      Stats{Fanout: map[int]int{}},
+		nodeSize:   int64(nodeSize),
+		keySize:    int64(reflect.TypeFor[K]().Size()),
+		valSize:    int64(reflect.TypeFor[T]().Size()),
+		seenValues: map[any]struct{}{},
+	}
+}
+
+// add accounts for a single node. val is the node's value pointer, or nil,
+// and childBytes the footprint of its child storage.
+func (c *statsCollector) add(depth int, textCap int, textLen int, end bool, val any, children int, childBytes int64) {
+	c.stats.EstimatedBytes += c.nodeSize + int64(textCap)*c.keySize + childBytes
+	if val != nil {
+		if _, ok := c.seenValues[val]; !ok {
+			c.seenValues[val] = struct{}{}
+			c.stats.EstimatedBytes += c.valSize
+		}
+	}
+	if depth == 0 {
+		// the root only contributes its memory
+		return
+	}
+	c.stats.Nodes++
+	c.stats.Fanout[children]++
+	c.stats.TextElements += textLen
+	c.stats.MaxDepth = max(c.stats.MaxDepth, depth)
+	if end {
+		c.stats.EndNodes++
+		c.depthSum += depth
+	} else {
+		c.stats.IntermediateNodes++
+	}
+}
+
+func (c *statsCollector) result() Stats {
+	c.stats.Keys = c.stats.EndNodes
+	if c.stats.EndNodes > 0 {
+		c.stats.AvgDepth = float64(c.depthSum) / float64(c.stats.EndNodes)
+	}
+	return c.stats
+}
+
+// estimateMapBytes approximates the memory of a map with n entries of pointer values,
+// assuming buckets of 8 slots filled to the Go load factor of 6.5.
+func estimateMapBytes(n int, keySize int64) int64 {
+	const header = 48
+	buckets := int64(1)
+	for float64(n) > 6.5*float64(buckets) {
+		buckets *= 2
+	}
+	if n == 0 {
+		return header
+	}
+	return header + buckets*(8+8*keySize+8*8+8)
+}
+
+// Stats walks the whole tree and returns its statistics.
+func (t *Tree[K, T]) Stats() Stats {
+	c := newStatsCollector[K, T](reflect.TypeFor[Node[K, T]]().Size())
+	var visit func(node *Node[K, T], depth int)
+	visit = func(node *Node[K, T], depth int) {
+		var val any
+		if node.Val != nil {
+			val = node.Val
+		}
+		c.add(depth, cap(node.Text), len(node.Text), node.End, val, node.children.len(), node.children.sizeBytes(c.keySize))
+		node.children.each(func(_ K, child *Node[K, T]) bool {
+			visit(child, depth+1)
+			return true
+		})
+	}
+	visit(t.Root, 0)
+	return c.result()
+}
+
+// Stats walks the whole tree and returns its statistics.
+// Writers are paused during the walk, as in Snapshot, so the statistics are consistent.
+func (t *ConcurrentTree[K, T]) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := newStatsCollector[K, T](reflect.TypeFor[ConcurrentNode[K, T]]().Size())
+	var visit func(node *ConcurrentNode[K, T], depth int)
+	visit = func(node *ConcurrentNode[K, T], depth int) {
+		var val any
+		if node.Val != nil {
+			val = node.Val
+		}
+		c.add(depth, cap(node.Text), len(node.Text), node.End, val, node.children.len(), node.children.sizeBytes(c.keySize))
+		node.children.each(func(_ K, child *ConcurrentNode[K, T]) bool {
+			visit(child, depth+1)
+			return true
+		})
+	}
+	visit(t.Root, 0)
+	return c.result()
+}
+
+// countKeys returns the number of End nodes in node's subtree.
+func countKeys[K comparable, T any](node *Node[K, T]) int {
+	count := 0
+	if node.End {
+		count++
+	}
+	node.children.each(func(_ K, child *Node[K, T]) bool {
+		count += countKeys(child)
+		return true
+	})
+	return count
+}
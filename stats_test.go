@@ -0,0 +1,128 @@
+package lradix
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestTreeLen(t *testing.T) {
+	tree := NewTree[byte, int]()
+	steps := []struct {
+		action   string
+		key      string
+		expected int
+	}{
+		{"insert", "hello", 1},
+		{"insert", "help", 2},    // splits "hello"
+		{"insert", "hel", 3},     // existing intermediate node becomes a key
+		{"insert", "he", 4},      // split ending at the new key
+		{"insert", "hello", 4},   // overwrite
+		{"insert", "", 4},        // empty keys are ignored
+		{"delete", "hel", 3},     // node with children
+		{"delete", "hel", 3},     // already deleted
+		{"delete", "hello", 2},   // leaf
+		{"delete", "missing", 2}, // not stored
+		{"delete", "help", 1},
+		{"delete", "he", 0},
+	}
+	for _, step := range steps {
+		switch step.action {
+		case "insert":
+			tree.Insert([]byte(step.key), 0)
+		case "delete":
+			tree.Delete([]byte(step.key))
+		}
+		if tree.Len() != step.expected {
+			t.Errorf("after %s(%q) Len() = %d, expected %d", step.action, step.key, tree.Len(), step.expected)
+		}
+	}
+
+	// RemoveNode of a node outside the tree does not change the count
+	tree.Insert([]byte("a"), 1)
+	x := 1
+	tree.RemoveNode(NewNode([]byte("b"), &x))
+	if tree.Len() != 1 {
+		t.Errorf("Len() = %d after removing an external node, expected 1", tree.Len())
+	}
+	if tree.Clone().Len() != 1 {
+		t.Errorf("Clone().Len() = %d, expected 1", tree.Clone().Len())
+	}
+	data, _ := tree.MarshalBinary()
+	decoded := NewTree[byte, int]()
+	decoded.UnmarshalBinary(data)
+	if decoded.Len() != 1 {
+		t.Errorf("Len() = %d after decode, expected 1", decoded.Len())
+	}
+}
+
+func TestConcurrentTreeLen(t *testing.T) {
+	tree := NewConcurrentTree[rune, int]()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := []rune(fmt.Sprintf("key-%d", i%50))
+			tree.Insert(key, i)
+		}(i)
+	}
+	wg.Wait()
+	if tree.Len() != 50 {
+		t.Errorf("Len() = %d after concurrent inserts, expected 50", tree.Len())
+	}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tree.Delete([]rune(fmt.Sprintf("key-%d", i%25)))
+		}(i)
+	}
+	wg.Wait()
+	if tree.Len() != 25 {
+		t.Errorf("Len() = %d after concurrent deletes, expected 25", tree.Len())
+	}
+	if tree.Stats().Keys != tree.Len() {
+		t.Errorf("Stats().Keys = %d, Len() = %d", tree.Stats().Keys, tree.Len())
+	}
+	if tree.Snapshot().Len() != 25 {
+		t.Errorf("Snapshot().Len() = %d, expected 25", tree.Snapshot().Len())
+	}
+}
+
+func TestTreeStats(t *testing.T) {
+	tree := NewTree[byte, int]()
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2)
+	tree.Insert([]byte("world"), 3)
+	// └──ROOT
+	//    └──hel
+	//       └──lo
+	//       └──p
+	//    └──world
+
+	stats := tree.Stats()
+	if stats.Keys != 3 || stats.Nodes != 4 || stats.EndNodes != 3 || stats.IntermediateNodes != 1 {
+		t.Errorf("Stats() counts = %+v", stats)
+	}
+	if stats.MaxDepth != 2 {
+		t.Errorf("Stats().MaxDepth = %d, expected 2", stats.MaxDepth)
+	}
+	if expected := 5.0 / 3.0; stats.AvgDepth != expected {
+		t.Errorf("Stats().AvgDepth = %v, expected %v", stats.AvgDepth, expected)
+	}
+	if stats.Fanout[0] != 3 || stats.Fanout[2] != 1 {
+		t.Errorf("Stats().Fanout = %v, expected map[0:3 2:1]", stats.Fanout)
+	}
+	if stats.TextElements != 11 {
+		t.Errorf("Stats().TextElements = %d, expected 11", stats.TextElements)
+	}
+	if stats.EstimatedBytes <= 0 {
+		t.Errorf("Stats().EstimatedBytes = %d, expected a positive estimate", stats.EstimatedBytes)
+	}
+
+	empty := NewTree[byte, int]().Stats()
+	if empty.Keys != 0 || empty.Nodes != 0 || empty.AvgDepth != 0 {
+		t.Errorf("Stats() of empty tree = %+v", empty)
+	}
+}
@@ -16,27 +16,31 @@ package lradix
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 )
 
 // Node represents a node in the radix tree.
 // It contains the text fragment of type K, associated value of type T, and child nodes.
+// Children are accessed through GetChild, AddChild, RemoveChild, NumChildren and ForEachChild;
+// their storage adapts to the number of children instead of always allocating a map.
 type Node[K comparable, T any] struct {
-	Text     []K               // Text fragment for this node (of comparable type K)
-	Val      *T                // Value associated with this node (nil for intermediate nodes, of type T)
-	End      bool              // Whether this node represents the end of a complete key
-	Children map[K]*Node[K, T] // Child nodes indexed by first character (key type K)
-	Parent   *Node[K, T]       // Parent node for tree traversal
+	Text     []K                      // Text fragment for this node (of comparable type K)
+	Val      *T                       // Value associated with this node (derived by the ValuePolicy for intermediate nodes, of type T)
+	End      bool                     // Whether this node represents the end of a complete key
+	Parent   *Node[K, T]              // Parent node for tree traversal
+	children children[K, *Node[K, T]] // Child nodes indexed by first character (key type K)
+	seq      uint64                   // insertion sequence number of Val, used by the value policy
+	summary  any                      // cached Aggregator summary of the subtree, nil without an aggregator
 }
 
 // NewNode creates a new leaf node with the given text (type K) and value (type T).
 // A leaf node represents the end of a complete key.
 func NewNode[K comparable, T any](text []K, val *T) *Node[K, T] {
 	return &Node[K, T]{
-		Text:     text,
-		Val:      val,
-		End:      true,
-		Children: map[K]*Node[K, T]{},
+		Text: text,
+		Val:  val,
+		End:  true,
 	}
 }
 
@@ -44,10 +48,9 @@ func NewNode[K comparable, T any](text []K, val *T) *Node[K, T] {
 // An intermediate node does not represent the end of a complete key.
 func NewIntermediateNode[K comparable, T any](text []K, val *T) *Node[K, T] {
 	return &Node[K, T]{
-		Text:     text,
-		Val:      val,
-		End:      false,
-		Children: map[K]*Node[K, T]{},
+		Text: text,
+		Val:  val,
+		End:  false,
 	}
 }
 
@@ -57,44 +60,88 @@ func (n *Node[K, T]) AddChild(node *Node[K, T]) {
 	if len(node.Text) == 0 {
 		return
 	}
-	if n.Children == nil {
-		n.Children = map[K]*Node[K, T]{}
-	}
 	node.Parent = n
-	n.Children[node.Text[0]] = node
+	n.children.put(node.Text[0], node)
 }
 
 // GetChild retrieves a child node by its first character (type K).
 // Returns the child node and a boolean indicating if it was found.
 func (n *Node[K, T]) GetChild(head K) (*Node[K, T], bool) {
-	child, ok := n.Children[head]
-	return child, ok
+	return n.children.get(head)
+}
+
+// RemoveChild removes the child indexed by the given first character (type K), if any.
+// The removed child's parent pointer is left untouched.
+func (n *Node[K, T]) RemoveChild(head K) {
+	n.children.remove(head)
+}
+
+// NumChildren returns the number of child nodes.
+func (n *Node[K, T]) NumChildren() int {
+	return n.children.len()
+}
+
+// ForEachChild calls fn for every child node along with the first character indexing it.
+// Children are visited in storage order, which is sorted except for nodes with many non-byte children.
+// Iteration stops early when fn returns false.
+func (n *Node[K, T]) ForEachChild(fn func(head K, child *Node[K, T]) bool) {
+	n.children.each(fn)
+}
+
+// Children returns the child nodes indexed by their first character (type K), as the former
+// Children field of the node did. Replacing the field with this method is a breaking change:
+// code using n.Children must call n.Children() instead. The map is built on every call;
+// modifying it has no effect on the tree, so writers must use AddChild and RemoveChild.
+//
+// Deprecated: use GetChild, NumChildren and ForEachChild, which do not allocate.
+func (n *Node[K, T]) Children() map[K]*Node[K, T] {
+	children := make(map[K]*Node[K, T], n.children.len())
+	n.children.each(func(head K, child *Node[K, T]) bool {
+		children[head] = child
+		return true
+	})
+	return children
 }
 
 // Tree represents a radix tree data structure.
 // It provides efficient insertion and longest common prefix matching operations for keys of type K and values of type T.
 type Tree[K comparable, T any] struct {
 	Root *Node[K, T] // Root node of the tree
+	size int         // number of stored keys, maintained by Insert and RemoveNode
+	seq  uint64      // sequence number of the last Insert
+	opts treeOptions
+	// values derives the values of intermediate nodes, see ValuePolicy
+	values valuePolicy[T]
+	agg    aggregator[T] // maintains subtree summaries, nil unless created WithAggregator
 }
 
 // NewTree creates a new empty radix tree with keys of type K and values of type T.
-func NewTree[K comparable, T any]() *Tree[K, T] {
-	return &Tree[K, T]{
+// By default Insert copies key fragments into the tree; see WithZeroCopy.
+// Intermediate nodes carry the most recently inserted value below them; see WithValuePolicy.
+func NewTree[K comparable, T any](opts ...Option) *Tree[K, T] {
+	o := newTreeOptions(opts)
+	t := &Tree[K, T]{
+		opts:   o,
+		values: newValuePolicy[T](o),
+		agg:    newAggregator[T](o),
 		Root: &Node[K, T]{
-			Text:     []K{},
-			Children: map[K]*Node[K, T]{},
+			Text: []K{},
 		},
 	}
+	t.summarize(t.Root)
+	return t
 }
 
 // Insert inserts a key-value pair into the tree.
 // The key is represented as a slice of type K, and the value is of type T.
 // If the key already exists, it will be overwritten.
+// The key is copied unless the tree was created WithZeroCopy, so the caller may reuse it afterwards.
 // Returns the newly created node or nil if insertion failed.
 func (t *Tree[K, T]) Insert(str []K, val T) *Node[K, T] {
 	if len(str) == 0 {
 		return nil
 	}
+	t.seq++
 	mark := t.Root
 	index := 0
 	for index < len(str) {
@@ -103,28 +150,35 @@ func (t *Tree[K, T]) Insert(str []K, val T) *Node[K, T] {
 		next, ok := cur.GetChild(char)
 		if !ok {
 			// no match, add new node to current children
-			newNode := NewNode(str[index:], &val)
+			newNode := NewNode(ownText(t.opts, str[index:]), &val)
+			newNode.seq = t.seq
 			cur.AddChild(newNode)
+			t.size++
+			t.refresh(cur, newNode, true)
+			t.summarize(newNode)
 			return newNode
 		}
 		sharedPrefix := longestPrefix(next.Text, str[index:])
 		if sharedPrefix < len(next.Text) {
-			// partial match, split node
-			// use this insert val as common node val, because it is most recent
-			commonNode := NewIntermediateNode(next.Text[:sharedPrefix], &val)
+			// partial match, split node; the value policy fills in the common node's value
+			commonNode := NewIntermediateNode[K, T](next.Text[:sharedPrefix], nil)
 			cur.AddChild(commonNode)
-			if cur.Parent != nil {
-				// if not root, update parent val
-				cur.Val = &val
-			}
 			next.Text = next.Text[sharedPrefix:]
 			commonNode.AddChild(next)
 			if index+sharedPrefix < len(str) {
-				newNode := NewNode(str[index+sharedPrefix:], &val)
+				newNode := NewNode(ownText(t.opts, str[index+sharedPrefix:]), &val)
+				newNode.seq = t.seq
 				commonNode.AddChild(newNode)
+				t.size++
+				t.refresh(commonNode, newNode, true)
+				t.summarize(newNode)
 				return newNode
 			} else {
+				commonNode.Val, commonNode.seq = &val, t.seq
 				commonNode.End = true
+				t.size++
+				t.refresh(cur, commonNode, false)
+				t.summarize(commonNode)
 				return commonNode
 			}
 		}
@@ -132,17 +186,35 @@ func (t *Tree[K, T]) Insert(str []K, val T) *Node[K, T] {
 		index += sharedPrefix
 		mark = next
 	}
-	mark.Val = &val
+	if !mark.End {
+		t.size++
+	}
+	mark.Val, mark.seq = &val, t.seq
 	mark.End = true
+	t.refresh(mark.Parent, mark, false)
+	t.summarize(mark)
 	return mark
 }
 
+// Len returns the number of keys stored in the tree in O(1).
+// It counts keys added and removed through the tree's methods.
+func (t *Tree[K, T]) Len() int {
+	return t.size
+}
+
 // LongestCommonPrefixMatch finds the longest prefix in the tree that matches the given key.
 // It returns three values: the longest common prefix (slice of type K), associated value (pointer to type T),
 // and a boolean indicating whether it is an exact match.
 // This is the core operation for prefix-based routing and matching.
 func (t *Tree[K, T]) LongestCommonPrefixMatch(str []K) ([]K, *T, bool) {
-	commonPrefix := []K{}
+	length, val, exact := t.LongestCommonPrefixLength(str)
+	return append([]K{}, str[:length]...), val, exact
+}
+
+// LongestCommonPrefixLength is an allocation-free LongestCommonPrefixMatch: it returns the
+// length of the longest common prefix instead of a copy of it, which is always str[:length].
+// The value and exact flag are the same as those of LongestCommonPrefixMatch.
+func (t *Tree[K, T]) LongestCommonPrefixLength(str []K) (int, *T, bool) {
 	mark := t.Root
 	index := 0
 	for index < len(str) {
@@ -151,54 +223,313 @@ func (t *Tree[K, T]) LongestCommonPrefixMatch(str []K) ([]K, *T, bool) {
 		// no match，stop at current node
 		next, ok := cur.GetChild(char)
 		if !ok {
-			return commonPrefix, mark.Val, false
+			return index, mark.Val, false
 		}
 		mark = next
 		sharedPrefix := longestPrefix(next.Text, str[index:])
-		commonPrefix = append(commonPrefix, next.Text[:sharedPrefix]...)
 		if sharedPrefix < len(next.Text) {
 			// partial match, stop
-			return commonPrefix, mark.Val, false
+			return index + sharedPrefix, mark.Val, false
 		}
 		// full match, move to next node
 		index += sharedPrefix
 	}
-	return commonPrefix, mark.Val, mark.End
+	return index, mark.Val, mark.End
+}
+
+// LongestMatchFunc is a LongestCommonPrefixLength that skips values rejected by accept, such as
+// unhealthy backends: it returns the deepest node along the path of str whose value is accepted,
+// with the length of the prefix matched up to that node and whether it is an exact match.
+// A node matched only partially counts with the length matched inside it. Nil values are never
+// passed to accept. If no value is accepted, it returns 0, nil and false.
+func (t *Tree[K, T]) LongestMatchFunc(str []K, accept func(val *T) bool) (int, *T, bool) {
+	length, val, exact := 0, (*T)(nil), false
+	mark := t.Root
+	index := 0
+	for index < len(str) {
+		next, ok := mark.GetChild(str[index])
+		if !ok {
+			break
+		}
+		sharedPrefix := longestPrefix(next.Text, str[index:])
+		partial := sharedPrefix < len(next.Text)
+		index += sharedPrefix
+		if next.Val != nil && accept(next.Val) {
+			length, val, exact = index, next.Val, next.End && !partial && index == len(str)
+		}
+		if partial {
+			// partial match, stop
+			break
+		}
+		mark = next
+	}
+	return length, val, exact
+}
+
+// Get returns the value stored under exactly the given key.
+// Only nodes that represent the end of a complete key are reported; partial matches
+// and intermediate nodes return nil and false.
+func (t *Tree[K, T]) Get(str []K) (*T, bool) {
+	node := t.findNode(str)
+	if node == nil || !node.End {
+		return nil, false
+	}
+	return node.Val, true
+}
+
+// Delete removes exactly the given key from the tree and returns its previous value.
+// The node is located by key and removed through RemoveNode, so the same parent cleanup applies.
+// Returns nil and false if the key is not stored in the tree.
+func (t *Tree[K, T]) Delete(str []K) (*T, bool) {
+	node := t.findNode(str)
+	if node == nil || !node.End {
+		return nil, false
+	}
+	old := node.Val
+	t.RemoveNode(node)
+	return old, true
+}
+
+// findNode returns the node whose full key equals str, or nil if the key ends
+// inside a node's text or leaves the tree. The returned node may be intermediate.
+func (t *Tree[K, T]) findNode(str []K) *Node[K, T] {
+	if len(str) == 0 {
+		return nil
+	}
+	mark := t.Root
+	index := 0
+	for index < len(str) {
+		next, ok := mark.GetChild(str[index])
+		if !ok {
+			return nil
+		}
+		sharedPrefix := longestPrefix(next.Text, str[index:])
+		if sharedPrefix < len(next.Text) {
+			return nil
+		}
+		index += sharedPrefix
+		mark = next
+	}
+	return mark
+}
+
+// WalkPrefix calls fn for every stored key that starts with the given prefix, along with its value.
+// The prefix may end inside a node's text; all keys below that node are still visited.
+// Keys passed to fn are freshly built slices and may be retained by the caller.
+// Iteration stops early when fn returns false.
+func (t *Tree[K, T]) WalkPrefix(prefix []K, fn func(key []K, val *T) bool) {
+	mark := t.Root
+	key := []K{}
+	index := 0
+	for index < len(prefix) {
+		next, ok := mark.GetChild(prefix[index])
+		if !ok {
+			return
+		}
+		sharedPrefix := longestPrefix(next.Text, prefix[index:])
+		if sharedPrefix < len(next.Text) && index+sharedPrefix < len(prefix) {
+			// diverged inside the node, nothing below can match
+			return
+		}
+		key = append(key, mark.Text...)
+		index += sharedPrefix
+		mark = next
+	}
+	walkNode(mark, key, nil, fn)
+}
+
+// Walk calls fn for every stored key in the tree, along with its value.
+// Children are visited in storage order; use WalkOrdered for a deterministic, sorted walk.
+// Iteration stops early when fn returns false.
+func (t *Tree[K, T]) Walk(fn func(key []K, val *T) bool) {
+	walkNode(t.Root, []K{}, nil, fn)
+}
+
+// KeysWithPrefix returns every stored key that starts with the given prefix.
+func (t *Tree[K, T]) KeysWithPrefix(prefix []K) [][]K {
+	keys := [][]K{}
+	t.WalkPrefix(prefix, func(key []K, _ *T) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// WalkPath calls fn for every stored key that is a prefix of str, from shortest to longest.
+// Only nodes that represent the end of a complete key are reported. The key passed to fn
+// is a capacity-limited subslice of str. Iteration stops early when fn returns false.
+func (t *Tree[K, T]) WalkPath(str []K, fn func(key []K, val *T) bool) {
+	mark := t.Root
+	index := 0
+	for index < len(str) {
+		next, ok := mark.GetChild(str[index])
+		if !ok {
+			return
+		}
+		sharedPrefix := longestPrefix(next.Text, str[index:])
+		if sharedPrefix < len(next.Text) {
+			return
+		}
+		index += sharedPrefix
+		mark = next
+		if mark.End && !fn(str[:index:index], mark.Val) {
+			return
+		}
+	}
+}
+
+// AllPrefixMatches returns every stored key that is a prefix of str, from shortest to longest,
+// as matches carrying the prefix length and value. Exact is set for the match covering all of str.
+// Tree nodes carry no IDs, so the ID of every match is zero.
+func (t *Tree[K, T]) AllPrefixMatches(str []K) []Match[T] {
+	matches := []Match[T]{}
+	t.WalkPath(str, func(key []K, val *T) bool {
+		matches = append(matches, NewMatch(0, len(key), val, len(key) == len(str)))
+		return true
+	})
+	return matches
+}
+
+// walkNode visits node and its descendants depth-first, calling fn for every End node.
+// parentKey is the reconstructed key up to, but not including, node's own text.
+// Children are visited in the order given by compare, or in storage order if compare is nil.
+// Returns false if fn stopped the walk.
+func walkNode[K comparable, T any](node *Node[K, T], parentKey []K, compare func(a, b K) int, fn func(key []K, val *T) bool) bool {
+	// limit capacity so that sibling keys never share a backing array
+	key := append(parentKey[:len(parentKey):len(parentKey)], node.Text...)
+	if node.End && !fn(key, node.Val) {
+		return false
+	}
+	return eachChild(&node.children, compare, func(child *Node[K, T]) bool {
+		return walkNode(child, key, compare, fn)
+	})
 }
 
 // RemoveNode removes a node from the tree.
 // Only leaf nodes (nodes without children) can be removed.
 // When a leaf node is removed, its parent may also be removed if it becomes
 // an intermediate node with no children and doesn't represent a complete key.
+// An intermediate node left with a single child is merged into that child.
 // The node parameter is of type Node[K, T] with the same generic types as the tree.
 func (t *Tree[K, T]) RemoveNode(node *Node[K, T]) {
-	if len(node.Children) > 0 {
-		for _, v := range node.Children {
-			node.Val = v.Val
+	if node.children.len() > 0 {
+		if node.End {
+			t.size--
 		}
 		node.End = false
+		t.tidy(node)
 		return
 	}
 	parent := node.Parent
 	node.Parent = nil
 	if parent == nil {
-		// root node can't be removed
+		// root node can't be removed, but it may have lost its last child
+		t.summarize(node)
 		return
 	}
+	if node.End {
+		t.size--
+	}
 
-	delete(parent.Children, node.Text[0])
-	if len(parent.Children) == 0 && !parent.End {
+	parent.children.remove(node.Text[0])
+	if parent.children.len() == 0 && !parent.End {
 		t.RemoveNode(parent)
 	} else {
-		if parent.Parent == nil {
-			// root node needs not to be updated
-			return
+		t.tidy(parent)
+	}
+}
+
+// tidy restores the invariants around a node whose children or End flag changed:
+// the node is compacted if it no longer branches, then the values above the change are refreshed.
+func (t *Tree[K, T]) tidy(node *Node[K, T]) {
+	parent := node.Parent
+	t.compact(node)
+	if parent != nil && node.Parent == nil {
+		// merged into its only child, which keeps its own value
+		node = parent
+	}
+	t.refresh(node, nil, false)
+	t.summarize(node)
+}
+
+// compact merges an intermediate node that is left with a single child into that child,
+// so that every intermediate node keeps branching. The root and End nodes are never merged.
+// The child keeps its identity and value and takes over the node's place in the tree.
+func (t *Tree[K, T]) compact(node *Node[K, T]) {
+	if node.Parent == nil || node.End || node.children.len() != 1 {
+		return
+	}
+	child, _ := node.children.first()
+	child.Text = concatText(node.Text, child.Text)
+	node.Parent.AddChild(child)
+	node.Parent = nil
+	node.children.clear()
+}
+
+// refresh updates the value of node and of its intermediate ancestors according to the
+// value policy, stopping at the root, at an End node or as soon as a value is unchanged.
+// inserted is the node just stored below node, if any, which lets the built-in policies
+// skip re-reading the children, and leaf tells whether it is a new leaf; pass nil after a removal.
+func (t *Tree[K, T]) refresh(node *Node[K, T], inserted *Node[K, T], leaf bool) {
+	for ; node != nil && node.Parent != nil && !node.End; node = node.Parent {
+		if inserted != nil {
+			skip, insertedWins := t.values.skipOnInsert(node.Val, node.seq, inserted.seq, leaf)
+			if skip {
+				return
+			}
+			if insertedWins {
+				node.Val, node.seq = inserted.Val, inserted.seq
+				continue
+			}
 		}
-		for _, v := range parent.Children {
-			parent.Val = v.Val
-			break
+		acc := valueAcc[T]{policy: &t.values}
+		node.children.each(func(_ K, child *Node[K, T]) bool {
+			acc.add(child.Val, child.seq)
+			return true
+		})
+		val, seq := acc.result()
+		if val == node.Val && seq == node.seq {
+			return
 		}
+		node.Val, node.seq = val, seq
+	}
+}
+
+// summarize recomputes the cached subtree summaries of node and all of its ancestors.
+func (t *Tree[K, T]) summarize(node *Node[K, T]) {
+	if t.agg == nil {
+		return
+	}
+	for ; node != nil; node = node.Parent {
+		summary := t.agg.own(node.End, node.Val)
+		node.children.each(func(_ K, child *Node[K, T]) bool {
+			summary = t.agg.combine(summary, child.summary)
+			return true
+		})
+		node.summary = summary
+	}
+}
+
+// Clone returns a deep copy of the tree structure and key fragments.
+// Values are shared with the original tree.
+func (t *Tree[K, T]) Clone() *Tree[K, T] {
+	return &Tree[K, T]{Root: cloneNode(t.Root), size: t.size, seq: t.seq, opts: t.opts, values: t.values, agg: t.agg}
+}
+
+// cloneNode recursively copies a node and its children, setting parent pointers on the copies.
+func cloneNode[K comparable, T any](node *Node[K, T]) *Node[K, T] {
+	nc := &Node[K, T]{
+		Text:    slices.Clone(node.Text),
+		Val:     node.Val,
+		End:     node.End,
+		seq:     node.seq,
+		summary: node.summary,
 	}
+	node.children.each(func(_ K, child *Node[K, T]) bool {
+		nc.AddChild(cloneNode(child))
+		return true
+	})
+	return nc
 }
 
 // String returns a string representation of the tree structure.
@@ -216,37 +547,50 @@ func printNode[K comparable, T any](node *Node[K, T], prefix string, result *str
 		return
 	}
 
-	var displayText string
-	if len(node.Text) == 0 {
-		displayText = "ROOT"
-	} else {
-		switch v := any(node.Text).(type) {
-		case []byte:
-			displayText = string(v)
-		case []rune:
-			displayText = string(v)
-		default:
-			displayText = fmt.Sprintf("%v", node.Text)
-		}
-	}
-
 	result.WriteString(prefix)
 	result.WriteString("└──")
-	result.WriteString(displayText)
+	result.WriteString(displayText(node.Text))
 
 	result.WriteString(" (val: ")
-	if node.Val == nil {
-		result.WriteString("nil")
-	} else {
-		result.WriteString(fmt.Sprintf("%v", *node.Val))
-	}
+	result.WriteString(displayValue(node.Val))
 	result.WriteString(")")
 	result.WriteString("\n")
 
 	newPrefix := prefix + "   "
-	for _, child := range node.Children {
+	eachChild(&node.children, compareStable[K], func(child *Node[K, T]) bool {
 		printNode(child, newPrefix, result)
+		return true
+	})
+}
+
+// displayText returns the printable form of a node's text fragment.
+// []byte and []rune texts are shown as strings, other key types with %v, and the root as ROOT.
+func displayText[K comparable](text []K) string {
+	if len(text) == 0 {
+		return "ROOT"
+	}
+	switch v := any(text).(type) {
+	case []byte:
+		return string(v)
+	case []rune:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", text)
+	}
+}
+
+// displayValue returns the printable form of a node's value, or nil if it has none.
+func displayValue[T any](val *T) string {
+	if val == nil {
+		return "nil"
 	}
+	return fmt.Sprintf("%v", *val)
+}
+
+// concatText returns a new slice holding a followed by b, leaving both inputs untouched.
+func concatText[K comparable](a, b []K) []K {
+	text := make([]K, 0, len(a)+len(b))
+	return append(append(text, a...), b...)
 }
 
 // longestPrefix returns the length of the longest common prefix between two slices of type K.
@@ -1,6 +1,8 @@
 package lradix
 
 import (
+	"math/rand"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -26,8 +28,8 @@ func TestInsert(t *testing.T) {
 	}
 
 	// Check that children exist
-	if len(tree.Root.Children) != 1 {
-		t.Errorf("Expected 1 child, got %d", len(tree.Root.Children))
+	if len(tree.Root.Children()) != 1 {
+		t.Errorf("Expected 1 child, got %d", len(tree.Root.Children()))
 	}
 
 	// Test 3: Insert common prefix strings
@@ -35,7 +37,7 @@ func TestInsert(t *testing.T) {
 	tree.Insert([]byte("helper"), 5)
 
 	// Should have more children now due to common prefix splitting
-	if len(tree.Root.Children) == 0 {
+	if len(tree.Root.Children()) == 0 {
 		t.Error("Root should have children after inserting multiple strings")
 	}
 }
@@ -161,7 +163,7 @@ func TestNestedPrefixSplitting(t *testing.T) {
 		input    string
 		expected int
 	}{
-		{"a", 2},
+		{"a", 1},
 		{"ab", 2},
 		{"abc", 3},
 		{"abcd", 4},
@@ -170,7 +172,7 @@ func TestNestedPrefixSplitting(t *testing.T) {
 		{"abcdefg", 6}, // Should match the longest prefix
 		{"abcx", 3},    // Should match abc
 		{"abx", 2},     // Should match ab
-		{"ax", 2},      // Should match a
+		{"ax", 1},      // Should match a
 	}
 
 	for _, tc := range testCases {
@@ -242,7 +244,7 @@ func TestOverlappingPrefixes(t *testing.T) {
 		input    string
 		expected int
 	}{
-		{"inter", 5},
+		{"inter", 1},
 		{"internet", 2},
 		{"interview", 3},
 		{"interrupt", 4},
@@ -252,8 +254,8 @@ func TestOverlappingPrefixes(t *testing.T) {
 		{"interv", 3},  // Should match interview
 		{"interru", 4}, // Should match interrupt
 		{"interne", 2}, // Should match internet
-		{"inte", 5},    // Should match inter
-		{"int", 5},     // Should match inter
+		{"inte", 1},    // Should match inter
+		{"int", 1},     // Should match inter
 	}
 
 	for _, tc := range testCases {
@@ -374,7 +376,7 @@ func TestSpecialCharactersAndUnicode(t *testing.T) {
 		{"hello😊x", 3}, // Should match hello😊
 		{"hello\n", 4}, // Should match hello\nworld
 		{"hello\t", 5}, // Should match hello\tworld
-		{"hello", 3},   // Should match hello😊
+		{"hello", 5},   // Should match hello\tworld, the most recent key below hello
 	}
 
 	for _, tc := range testCases {
@@ -445,14 +447,14 @@ func TestRemoveNode(t *testing.T) {
 	tree.Insert([]byte("world"), 2)
 
 	// Verify initial state
-	if len(tree.Root.Children) != 2 {
-		t.Errorf("Expected 2 children, got %d", len(tree.Root.Children))
+	if len(tree.Root.Children()) != 2 {
+		t.Errorf("Expected 2 children, got %d", len(tree.Root.Children()))
 	}
 
 	// Remove one node
 	tree.RemoveNode(node1)
-	if len(tree.Root.Children) != 1 {
-		t.Errorf("Expected 1 child after removal, got %d", len(tree.Root.Children))
+	if len(tree.Root.Children()) != 1 {
+		t.Errorf("Expected 1 child after removal, got %d", len(tree.Root.Children()))
 	}
 
 	// Verify the remaining node still works
@@ -475,8 +477,8 @@ func TestRemoveNodeWithParentCleanup(t *testing.T) {
 	node3 := tree.Insert([]byte("helper"), 3)
 
 	// Verify initial structure
-	if len(tree.Root.Children) != 1 {
-		t.Errorf("Expected 1 child at root, got %d", len(tree.Root.Children))
+	if len(tree.Root.Children()) != 1 {
+		t.Errorf("Expected 1 child at root, got %d", len(tree.Root.Children()))
 	}
 
 	//fmt.Println(tree.String())
@@ -498,8 +500,8 @@ func TestRemoveNodeWithParentCleanup(t *testing.T) {
 	}
 
 	// Verify no children remain at root
-	if len(tree.Root.Children) != 1 {
-		t.Errorf("Expected 1 child at root, got %d", len(tree.Root.Children))
+	if len(tree.Root.Children()) != 1 {
+		t.Errorf("Expected 1 child at root, got %d", len(tree.Root.Children()))
 	}
 }
 
@@ -526,8 +528,8 @@ func TestRemoveNonexistentNode(t *testing.T) {
 	tree.RemoveNode(externalNode)
 
 	// Tree should remain unchanged
-	if len(tree.Root.Children) != 0 {
-		t.Errorf("Expected 0 children, got %d", len(tree.Root.Children))
+	if len(tree.Root.Children()) != 0 {
+		t.Errorf("Expected 0 children, got %d", len(tree.Root.Children()))
 	}
 }
 
@@ -560,7 +562,7 @@ func TestRemoveNodeComplexTree(t *testing.T) {
 		{"ruber", 5},
 		{"rubicon", 6},
 		{"roma", 1},       // Should match romanus
-		{"rub", 7},        // Should match rubicon
+		{"rub", 6},        // Should match rubicon
 		{"rubicundus", 6}, // Should match rubicon
 		{"rube", 5},       // Should match ruber
 	}
@@ -602,3 +604,468 @@ func TestRemoveIntermidiateNodeCleanup(t *testing.T) {
 		t.Errorf("Expected 3, got %v", *result)
 	}
 }
+
+func TestGet(t *testing.T) {
+	tree := NewTree[byte, int]()
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2)
+	tree.Insert([]byte("he"), 3)
+
+	testCases := []struct {
+		input    string
+		expected int
+		found    bool
+	}{
+		{"hello", 1, true},
+		{"help", 2, true},
+		{"he", 3, true},
+		{"hel", 0, false},    // Intermediate node is not a stored key
+		{"hell", 0, false},   // Ends inside a node's text
+		{"hellox", 0, false}, // Longer than any stored key
+		{"world", 0, false},  // No match
+		{"", 0, false},       // Empty key is never stored
+	}
+
+	for _, tc := range testCases {
+		result, found := tree.Get([]byte(tc.input))
+		if found != tc.found {
+			t.Errorf("Get(%q) found = %v, expected %v", tc.input, found, tc.found)
+		} else if found && (result == nil || *result != tc.expected) {
+			t.Errorf("Get(%q) = %v, expected %d", tc.input, result, tc.expected)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tree := NewTree[byte, int]()
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2)
+	tree.Insert([]byte("helper"), 3)
+
+	// Deleting a key that is not stored has no effect
+	if _, ok := tree.Delete([]byte("hel")); ok {
+		t.Error("Delete(hel) should not delete an intermediate node")
+	}
+	if _, ok := tree.Delete([]byte("world")); ok {
+		t.Error("Delete(world) should not delete a missing key")
+	}
+
+	// Delete a leaf and clean up
+	old, ok := tree.Delete([]byte("helper"))
+	if !ok || old == nil || *old != 3 {
+		t.Errorf("Delete(helper) = %v, %v, expected 3, true", old, ok)
+	}
+	if _, ok := tree.Get([]byte("helper")); ok {
+		t.Error("Get(helper) should fail after delete")
+	}
+
+	// Delete a key that still has children below it
+	tree.Insert([]byte("helper"), 3)
+	old, ok = tree.Delete([]byte("help"))
+	if !ok || old == nil || *old != 2 {
+		t.Errorf("Delete(help) = %v, %v, expected 2, true", old, ok)
+	}
+	if _, ok := tree.Get([]byte("help")); ok {
+		t.Error("Get(help) should fail after delete")
+	}
+	if result, ok := tree.Get([]byte("helper")); !ok || *result != 3 {
+		t.Errorf("Get(helper) = %v, %v, expected 3, true", result, ok)
+	}
+
+	// Deleting twice reports nothing the second time
+	if _, ok := tree.Delete([]byte("help")); ok {
+		t.Error("Delete(help) should fail the second time")
+	}
+
+	tree.Delete([]byte("hello"))
+	tree.Delete([]byte("helper"))
+	if tree.Root.NumChildren() != 0 {
+		t.Errorf("Expected 0 children after deleting all keys, got %d", tree.Root.NumChildren())
+	}
+}
+
+func TestWalkPrefix(t *testing.T) {
+	tree := NewTree[byte, int]()
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2)
+	tree.Insert([]byte("helper"), 3)
+	tree.Insert([]byte("world"), 4)
+
+	testCases := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"", []string{"hello", "help", "helper", "world"}},
+		{"he", []string{"hello", "help", "helper"}}, // Prefix ends inside a node
+		{"hel", []string{"hello", "help", "helper"}},
+		{"help", []string{"help", "helper"}},
+		{"helpe", []string{"helper"}},
+		{"helper", []string{"helper"}},
+		{"helpers", []string{}}, // Longer than any stored key
+		{"hex", []string{}},     // Diverges inside a node
+		{"x", []string{}},       // No match
+	}
+
+	for _, tc := range testCases {
+		got := map[string]int{}
+		tree.WalkPrefix([]byte(tc.prefix), func(key []byte, val *int) bool {
+			got[string(key)] = *val
+			return true
+		})
+		if len(got) != len(tc.expected) {
+			t.Errorf("WalkPrefix(%q) = %v, expected %v", tc.prefix, got, tc.expected)
+			continue
+		}
+		for _, key := range tc.expected {
+			if _, ok := got[key]; !ok {
+				t.Errorf("WalkPrefix(%q) = %v, missing %q", tc.prefix, got, key)
+			}
+		}
+	}
+
+	// Early stop
+	count := 0
+	tree.WalkPrefix([]byte("hel"), func(key []byte, val *int) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Errorf("Expected walk to stop after 1 key, got %d", count)
+	}
+
+	keys := tree.KeysWithPrefix([]byte("help"))
+	if len(keys) != 2 {
+		t.Errorf("KeysWithPrefix(help) = %q, expected 2 keys", keys)
+	}
+}
+
+func TestWalkPath(t *testing.T) {
+	tree := NewTree[byte, int]()
+	tree.Insert([]byte("a"), 1)
+	tree.Insert([]byte("abc"), 3)
+	tree.Insert([]byte("abcde"), 5)
+	tree.Insert([]byte("abx"), 6) // Splits "bc" and creates intermediate "b"
+
+	testCases := []struct {
+		input    string
+		expected []int // lengths of the reported prefixes
+	}{
+		{"abcdef", []int{1, 3, 5}},
+		{"abcde", []int{1, 3, 5}},
+		{"abcd", []int{1, 3}},
+		{"ab", []int{1}}, // Intermediate node is not reported
+		{"abxy", []int{1, 3}},
+		{"b", []int{}},
+	}
+
+	for _, tc := range testCases {
+		matches := tree.AllPrefixMatches([]byte(tc.input))
+		if len(matches) != len(tc.expected) {
+			t.Errorf("AllPrefixMatches(%q) returned %d matches, expected %v", tc.input, len(matches), tc.expected)
+			continue
+		}
+		for i, match := range matches {
+			if match.MatchLength != tc.expected[i] {
+				t.Errorf("AllPrefixMatches(%q)[%d] length = %d, expected %d", tc.input, i, match.MatchLength, tc.expected[i])
+			}
+			if match.Exact != (match.MatchLength == len(tc.input)) {
+				t.Errorf("AllPrefixMatches(%q)[%d] exact = %v", tc.input, i, match.Exact)
+			}
+			if match.Value == nil {
+				t.Errorf("AllPrefixMatches(%q)[%d] value = nil", tc.input, i)
+			}
+		}
+	}
+
+	// Early stop and key content
+	keys := []string{}
+	tree.WalkPath([]byte("abcdef"), func(key []byte, val *int) bool {
+		keys = append(keys, string(key))
+		return len(keys) < 2
+	})
+	if strings.Join(keys, ",") != "a,abc" {
+		t.Errorf("WalkPath(abcdef) keys = %v, expected [a abc]", keys)
+	}
+}
+
+func TestClone(t *testing.T) {
+	tree := NewTree[byte, int]()
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2)
+
+	clone := tree.Clone()
+	if clone.String() != tree.String() {
+		t.Errorf("Clone() =\n%s\nexpected\n%s", clone.String(), tree.String())
+	}
+
+	// Modifications of either tree are not visible in the other
+	clone.Insert([]byte("world"), 3)
+	tree.Delete([]byte("hello"))
+	if _, ok := tree.Get([]byte("world")); ok {
+		t.Error("Insert into clone leaked into the original tree")
+	}
+	if _, ok := clone.Get([]byte("hello")); !ok {
+		t.Error("Delete from the original tree leaked into the clone")
+	}
+
+	// Parent pointers point into the clone
+	node := clone.findNode([]byte("help"))
+	for node.Parent != nil {
+		node = node.Parent
+	}
+	if node != clone.Root {
+		t.Error("Clone parent pointers do not lead to the clone root")
+	}
+}
+
+func TestLongestCommonPrefixLength(t *testing.T) {
+	tree := NewTree[byte, int]()
+	for i, key := range []string{"hello", "help", "world", "hi"} {
+		tree.Insert([]byte(key), i)
+	}
+	testCases := []struct {
+		query    string
+		expected int
+		exact    bool
+	}{
+		{"", 0, false},
+		{"hello", 5, true},
+		{"hello world", 5, false},
+		{"hel", 3, false},
+		{"helium", 3, false},
+		{"hi", 2, true},
+		{"x", 0, false},
+	}
+	for _, tc := range testCases {
+		length, val, exact := tree.LongestCommonPrefixLength([]byte(tc.query))
+		if length != tc.expected || exact != tc.exact {
+			t.Errorf("LongestCommonPrefixLength(%q) = %d, %v, expected %d, %v", tc.query, length, exact, tc.expected, tc.exact)
+		}
+		prefix, matchVal, matchExact := tree.LongestCommonPrefixMatch([]byte(tc.query))
+		if string(prefix) != tc.query[:length] || matchVal != val || matchExact != exact {
+			t.Errorf("LongestCommonPrefixMatch(%q) = %q, %v, %v, inconsistent with length %d", tc.query, prefix, matchVal, matchExact, length)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tree.LongestCommonPrefixLength([]byte("hello world"))
+	})
+	if allocs != 0 {
+		t.Errorf("LongestCommonPrefixLength allocated %.1f times per run, expected 0", allocs)
+	}
+}
+
+func TestLongestMatchFunc(t *testing.T) {
+	tree := NewTree[byte, int]()
+	// help is inserted after hello, so the intermediate node "hel" carries its value
+	for i, key := range []string{"a", "ab", "abc", "abd", "hello", "help"} {
+		tree.Insert([]byte(key), []int{1, 2, 3, 4, 6, 7}[i])
+	}
+	odd := func(val *int) bool {
+		return *val%2 == 1
+	}
+	testCases := []struct {
+		query    string
+		length   int
+		expected int // zero for a nil value
+		exact    bool
+	}{
+		{"abc", 3, 3, true},
+		{"abd", 1, 1, false},
+		{"abx", 1, 1, false},
+		{"a", 1, 1, true},
+		{"b", 0, 0, false},
+		{"helium", 3, 7, false}, // the intermediate node "hel" carries help's value
+		{"hex", 2, 7, false},
+		{"hello", 3, 7, false},
+		{"help", 4, 7, true},
+	}
+	for _, tc := range testCases {
+		length, val, exact := tree.LongestMatchFunc([]byte(tc.query), odd)
+		got := 0
+		if val != nil {
+			got = *val
+		}
+		if length != tc.length || got != tc.expected || exact != tc.exact {
+			t.Errorf("LongestMatchFunc(%q) = %d, %d, %v, expected %d, %d, %v", tc.query, length, got, exact, tc.length, tc.expected, tc.exact)
+		}
+	}
+
+	// accepting every value matches LongestCommonPrefixLength wherever it reports a value
+	all := func(*int) bool { return true }
+	for _, query := range []string{"abc", "abd", "abx", "helium", "hex", "help"} {
+		length, val, exact := tree.LongestMatchFunc([]byte(query), all)
+		expectedLength, expectedVal, expectedExact := tree.LongestCommonPrefixLength([]byte(query))
+		if length != expectedLength || val != expectedVal || exact != expectedExact {
+			t.Errorf("LongestMatchFunc(%q) accepting everything = %d, %v, %v, expected %d, %v, %v",
+				query, length, val, exact, expectedLength, expectedVal, expectedExact)
+		}
+	}
+}
+
+func BenchmarkLongestCommonPrefixLength(b *testing.B) {
+	keys := benchmarkKeys[byte](50000, 256)
+	tree := buildBenchmarkTree(keys, "adaptive")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.LongestCommonPrefixLength(keys[i%len(keys)])
+	}
+	b.StopTimer()
+	if allocs := testing.AllocsPerRun(100, func() { tree.LongestCommonPrefixLength(keys[0]) }); allocs != 0 {
+		b.Fatalf("LongestCommonPrefixLength allocated %.1f times per run, expected 0", allocs)
+	}
+}
+
+func TestInsertCopiesKey(t *testing.T) {
+	buf := []byte("hello")
+	tree := NewTree[byte, int]()
+	tree.Insert(buf, 1)
+	copy(buf, "help!")
+	tree.Insert(buf[:4], 2) // splits "hello", reusing the same buffer
+	copy(buf, "xxxxx")
+
+	for _, tc := range []struct {
+		key      string
+		expected int
+	}{
+		{"hello", 1},
+		{"help", 2},
+	} {
+		if val, ok := tree.Get([]byte(tc.key)); !ok || *val != tc.expected {
+			t.Errorf("Get(%q) = %v, %v after reusing the key buffer, expected %d", tc.key, val, ok, tc.expected)
+		}
+	}
+	if _, ok := tree.Get([]byte("xxxxx")); ok {
+		t.Error("Get(xxxxx) found the mutated buffer contents")
+	}
+	if clone := tree.Clone(); !reflect.DeepEqual(clone.opts, tree.opts) {
+		t.Error("Clone did not keep the tree options")
+	}
+}
+
+func TestInsertZeroCopy(t *testing.T) {
+	buf := []byte("hello")
+	tree := NewTree[byte, int](WithZeroCopy())
+	node := tree.Insert(buf, 1)
+	if &node.Text[0] != &buf[0] {
+		t.Error("Expected WithZeroCopy to store the caller's key slice")
+	}
+	// mutating the key afterwards corrupts the tree, which is the caller's responsibility
+	copy(buf, "jello")
+	if string(node.Text) != "jello" {
+		t.Errorf("node text = %q, expected the zero-copy tree to observe the mutated key", node.Text)
+	}
+}
+
+func TestValuePolicy(t *testing.T) {
+	sum := WithValueReducer(func(children []*int) *int {
+		total := 0
+		for _, v := range children {
+			if v != nil {
+				total += *v
+			}
+		}
+		return &total
+	})
+	testCases := []struct {
+		name     string
+		opt      Option
+		inserted []int // values of "hel" and "he" after inserting hello=1, help=2, hex=3
+		removed  []int // after removing hex, which merges "he" into "hel"
+	}{
+		{"most recent", WithValuePolicy(ValueMostRecent), []int{2, 3}, []int{2, 2}},
+		{"first inserted", WithValuePolicy(ValueFirstInserted), []int{1, 1}, []int{1, 1}},
+		{"none", WithValuePolicy(ValueNone), []int{0, 0}, []int{0, 0}},
+		{"reducer", sum, []int{3, 6}, []int{3, 3}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tree := NewTree[byte, int](tc.opt)
+			tree.Insert([]byte("hello"), 1)
+			tree.Insert([]byte("help"), 2)
+			hex := tree.Insert([]byte("hex"), 3)
+			checkPolicyValues(t, tree, tc.inserted)
+
+			tree.RemoveNode(hex)
+			checkPolicyValues(t, tree, tc.removed)
+			if val, ok := tree.Get([]byte("hello")); !ok || *val != 1 {
+				t.Errorf("Get(hello) = %v, %v, expected End nodes to keep their own value", val, ok)
+			}
+		})
+	}
+}
+
+// checkPolicyValues checks the values returned for partial matches ending in "hel" and in "he",
+// where 0 stands for no value.
+func checkPolicyValues(t *testing.T, tree *Tree[byte, int], expected []int) {
+	t.Helper()
+	for i, query := range []string{"hel", "he"} {
+		_, val, _ := tree.LongestCommonPrefixMatch([]byte(query))
+		got := 0
+		if val != nil {
+			got = *val
+		}
+		if got != expected[i] {
+			t.Errorf("LongestCommonPrefixMatch(%q) value = %d, expected %d\n%s", query, got, expected[i], tree.String())
+		}
+	}
+}
+
+func TestValuePolicyRandom(t *testing.T) {
+	for _, policy := range []ValuePolicy{ValueMostRecent, ValueFirstInserted, ValueNone} {
+		t.Run(policy.String(), func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			tree := NewTree[byte, int](WithValuePolicy(policy))
+			for i := 0; i < 2000; i++ {
+				key := []byte(randomKey(r))
+				if r.Intn(3) == 0 {
+					tree.Delete(key)
+				} else {
+					tree.Insert(key, i)
+				}
+				checkIntermediateValues(t, tree.Root, policy)
+				if t.Failed() {
+					t.Fatalf("step %d:\n%s", i, tree.String())
+				}
+			}
+		})
+	}
+}
+
+// checkIntermediateValues verifies that every intermediate node below node holds the value
+// the policy picks among its children, by insertion sequence number.
+func checkIntermediateValues(t *testing.T, node *Node[byte, int], policy ValuePolicy) {
+	t.Helper()
+	var expected *Node[byte, int]
+	node.ForEachChild(func(_ byte, child *Node[byte, int]) bool {
+		checkIntermediateValues(t, child, policy)
+		switch {
+		case child.Val == nil:
+		case expected == nil,
+			policy == ValueMostRecent && child.seq > expected.seq,
+			policy == ValueFirstInserted && child.seq < expected.seq:
+			expected = child
+		}
+		return true
+	})
+	if node.End || node.Parent == nil {
+		return
+	}
+	var val *int
+	if expected != nil && policy != ValueNone {
+		val = expected.Val
+	}
+	if node.Val != val {
+		t.Errorf("intermediate node %q holds %s, expected %s", node.Text, displayValue(node.Val), displayValue(val))
+	}
+}
+
+func TestValueReducerTypeMismatch(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected NewTree to panic on a reducer for another value type")
+		}
+	}()
+	NewTree[byte, int](WithValueReducer(func(children []*string) *string { return nil }))
+}
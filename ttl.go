@@ -0,0 +1,174 @@
+package lradix
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInvalidInterval is returned by Start when the janitor interval is not positive.
+var ErrInvalidInterval = errors.New("lradix: janitor interval must be positive")
+
+// WithTTL sets the default TTL of the values inserted into a ConcurrentTree with Insert.
+// Expired values are hidden from lookups right away and removed by RemoveExpired, which the
+// janitor started by Start runs periodically. Until then they still count towards Len.
+// Zero means values never expire. Tree ignores this option.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *treeOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithClock replaces time.Now as the source of the current time used for TTLs,
+// so that tests can control when values expire.
+func WithClock(now func() time.Time) Option {
+	return func(o *treeOptions) {
+		o.clock = now
+	}
+}
+
+// InsertWithTTL inserts a key-value pair like Insert, but the value expires after ttl instead of
+// the tree's default TTL. A ttl of zero or less stores a value that never expires. Inserting a key
+// again replaces both its value and its expiry time.
+func (t *ConcurrentTree[K, T]) InsertWithTTL(str []K, val T, ttl time.Duration) *ConcurrentNode[K, T] {
+	return t.put(str, ttl, overwrite(val))
+}
+
+// RemoveExpired removes every key whose value has expired through RemoveNode, with the same
+// parent cleanup, and returns the number of keys removed. A key inserted again concurrently
+// with a new expiry time is kept, as are pinned keys until they are unpinned.
+func (t *ConcurrentTree[K, T]) RemoveExpired() int {
+	if !t.expiring.Load() {
+		return 0
+	}
+	clock := t.now().UnixNano()
+	var found []*ConcurrentNode[K, T]
+	var visit func(node *ConcurrentNode[K, T])
+	visit = func(node *ConcurrentNode[K, T]) {
+		node.RLock()
+		if node.End && expired(node.expires, clock) {
+			found = append(found, node)
+		}
+		children := make([]*ConcurrentNode[K, T], 0, node.children.len())
+		node.children.each(func(_ K, child *ConcurrentNode[K, T]) bool {
+			children = append(children, child)
+			return true
+		})
+		node.RUnlock()
+		for _, child := range children {
+			visit(child)
+		}
+	}
+	visit(t.Root)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	isExpired := func(node *ConcurrentNode[K, T]) bool {
+		return node.End && expired(node.expires, clock)
+	}
+	removed := 0
+	for _, node := range found {
+		if ok, _ := t.removeNode(node, isExpired); ok {
+			removed++
+		}
+	}
+	return removed
+}
+
+// Start runs a janitor goroutine that calls RemoveExpired every interval until Stop is called.
+// Starting a tree whose janitor is already running has no effect. It returns ErrInvalidInterval
+// if interval is zero or negative.
+func (t *ConcurrentTree[K, T]) Start(interval time.Duration) error {
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+	t.janitor.start(interval, func() { t.RemoveExpired() })
+	return nil
+}
+
+// Stop stops the janitor started by Start and waits for it to exit.
+// Stopping a tree without a running janitor has no effect.
+func (t *ConcurrentTree[K, T]) Stop() {
+	t.janitor.stop()
+}
+
+// janitor runs a function periodically on a background goroutine.
+type janitor struct {
+	mu   sync.Mutex
+	quit chan struct{} // closed to stop the goroutine, nil while it is not running
+	done chan struct{} // closed by the goroutine when it exits
+}
+
+func (j *janitor) start(interval time.Duration, fn func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.quit != nil {
+		return
+	}
+	j.quit, j.done = make(chan struct{}), make(chan struct{})
+	go func(quit, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}(j.quit, j.done)
+}
+
+func (j *janitor) stop() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.quit == nil {
+		return
+	}
+	close(j.quit)
+	<-j.done
+	j.quit, j.done = nil, nil
+}
+
+// now returns the current time of the tree's clock.
+func (t *ConcurrentTree[K, T]) now() time.Time {
+	if t.opts.clock != nil {
+		return t.opts.clock()
+	}
+	return time.Now()
+}
+
+// expiryTime returns the expiry time in Unix nanoseconds of a value inserted now with the given TTL,
+// or zero if it never expires.
+func (t *ConcurrentTree[K, T]) expiryTime(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	t.expiring.Store(true)
+	return t.now().Add(ttl).UnixNano()
+}
+
+// expiryClock returns the current time in Unix nanoseconds to check expiry times against,
+// or zero if no value was ever inserted with a TTL, which saves reading the clock.
+func (t *ConcurrentTree[K, T]) expiryClock() int64 {
+	if !t.expiring.Load() {
+		return 0
+	}
+	return t.now().UnixNano()
+}
+
+// expired reports whether a value expiring at expires has expired at clock, as returned by expiryClock.
+func expired(expires int64, clock int64) bool {
+	return expires != 0 && clock != 0 && clock >= expires
+}
+
+// liveVal returns the value of node, or nil if it has expired at clock.
+// The caller must hold the node's read lock.
+func liveVal[K comparable, T any](node *ConcurrentNode[K, T], clock int64) *T {
+	if expired(node.expires, clock) {
+		return nil
+	}
+	return node.Val
+}
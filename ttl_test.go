@@ -0,0 +1,175 @@
+package lradix
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for WithClock.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestConcurrentTreeTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tree := NewConcurrentTree[byte, int](WithTTL(10*time.Second), WithClock(clock.Now))
+	tree.Insert([]byte("hello"), 1)
+	tree.Insert([]byte("help"), 2)
+	tree.InsertWithTTL([]byte("world"), 3, 0)
+
+	clock.Advance(5 * time.Second)
+	tree.InsertWithTTL([]byte("hello"), 4, 20*time.Second)
+	if val, ok := tree.Get([]byte("help")); !ok || *val != 2 {
+		t.Errorf("Get(help) = %v, %v before expiry, expected 2, true", val, ok)
+	}
+
+	clock.Advance(5 * time.Second)
+	testCases := []struct {
+		key      string
+		length   int
+		expected int // zero for a nil value
+		exact    bool
+	}{
+		{"hello", 5, 4, true},
+		{"help", 4, 0, false},
+		{"help me", 4, 0, false},
+		{"hel", 3, 4, false},
+		{"world", 5, 3, true},
+	}
+	for _, tc := range testCases {
+		_, length, val, exact := tree.LongestCommonPrefixLength([]byte(tc.key))
+		got := 0
+		if val != nil {
+			got = *val
+		}
+		if length != tc.length || got != tc.expected || exact != tc.exact {
+			t.Errorf("LongestCommonPrefixLength(%q) = %d, %d, %v, expected %d, %d, %v",
+				tc.key, length, got, exact, tc.length, tc.expected, tc.exact)
+		}
+	}
+	if _, ok := tree.Get([]byte("help")); ok {
+		t.Error("Get(help) found an expired key")
+	}
+	for _, m := range tree.MultiLongestCommonPrefixMatch([]byte("help")) {
+		if m.Value != nil && *m.Value == 2 {
+			t.Errorf("MultiLongestCommonPrefixMatch(help) reported the expired value in %+v", m)
+		}
+	}
+
+	// the intermediate node "hel" carries the most recent value below it, which expires along with helium
+	tree.InsertWithTTL([]byte("helium"), 5, time.Second)
+	clock.Advance(time.Second)
+	if _, _, val, _ := tree.LongestCommonPrefixLength([]byte("hel")); val != nil {
+		t.Errorf("LongestCommonPrefixLength(hel) = %d, expected the expired value to be hidden", *val)
+	}
+
+	if n := tree.RemoveExpired(); n != 2 {
+		t.Errorf("RemoveExpired() = %d, expected 2", n)
+	}
+	if tree.Len() != 2 {
+		t.Errorf("Len() = %d after RemoveExpired, expected 2", tree.Len())
+	}
+	if n := tree.RemoveExpired(); n != 0 {
+		t.Errorf("RemoveExpired() = %d on a tree without expired keys, expected 0", n)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+	clock.Advance(time.Hour)
+	if _, ok := tree.Get([]byte("world")); !ok {
+		t.Error("Key inserted without a TTL expired")
+	}
+}
+
+func TestConcurrentTreeTTLEnumeration(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tree := NewConcurrentTree[byte, int](WithClock(clock.Now))
+	tree.InsertWithTTL([]byte("he"), 1, time.Second)
+	tree.Insert([]byte("hello"), 2)
+	tree.Insert([]byte("help"), 3)
+	tree.InsertWithTTL([]byte("helium"), 4, time.Second)
+	clock.Advance(time.Second)
+
+	if matches := tree.AllPrefixMatches([]byte("hello")); len(matches) != 1 || *matches[0].Value != 2 {
+		t.Errorf("AllPrefixMatches(hello) = %+v after expiry, expected only hello", matches)
+	}
+	keys := tree.KeysWithPrefix([]byte(""))
+	slices.SortFunc(keys, slices.Compare)
+	if !slices.EqualFunc(keys, [][]byte{[]byte("hello"), []byte("help")}, slices.Equal) {
+		t.Errorf("KeysWithPrefix() = %q after expiry, expected [hello help]", keys)
+	}
+	var walked []string
+	WalkConcurrentOrdered(tree, func(key []byte, _ *int) bool {
+		walked = append(walked, string(key))
+		return true
+	})
+	if !slices.Equal(walked, []string{"hello", "help"}) {
+		t.Errorf("WalkConcurrentOrdered visited %q after expiry, expected [hello help]", walked)
+	}
+
+	snapshot := tree.Snapshot()
+	if snapshot.Len() != 2 {
+		t.Errorf("Snapshot().Len() = %d after expiry, expected 2", snapshot.Len())
+	}
+	for _, key := range []string{"he", "helium"} {
+		if _, ok := snapshot.Get([]byte(key)); ok {
+			t.Errorf("Snapshot().Get(%s) found an expired key", key)
+		}
+	}
+	if _, val, _ := snapshot.LongestCommonPrefixLength([]byte("hel")); val == nil || *val != 3 {
+		t.Errorf("Snapshot().LongestCommonPrefixLength(hel) = %v, expected the most recent live value 3", val)
+	}
+	if err := snapshot.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentTreeJanitor(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tree := NewConcurrentTree[byte, int](WithTTL(time.Minute), WithClock(clock.Now))
+	for _, key := range []string{"apple", "apricot", "banana"} {
+		tree.Insert([]byte(key), len(key))
+	}
+	if err := tree.Start(0); !errors.Is(err, ErrInvalidInterval) {
+		t.Errorf("Start(0) error = %v, expected ErrInvalidInterval", err)
+	}
+	if err := tree.Start(time.Millisecond); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	tree.Start(time.Millisecond)
+	defer tree.Stop()
+
+	clock.Advance(2 * time.Minute)
+	deadline := time.Now().Add(5 * time.Second)
+	for tree.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if tree.Len() != 0 {
+		t.Errorf("Len() = %d, expected the janitor to remove every expired key", tree.Len())
+	}
+	tree.Stop()
+	tree.Stop()
+
+	tree.Insert([]byte("cherry"), 6)
+	clock.Advance(2 * time.Minute)
+	time.Sleep(5 * time.Millisecond)
+	if tree.Len() != 1 {
+		t.Errorf("Len() = %d, expected the stopped janitor to leave expired keys", tree.Len())
+	}
+}
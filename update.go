@@ -0,0 +1,74 @@
+package lradix
+
+// The conditional writes below decide what to store while holding the lock of the node that
+// holds or would hold the key, so they are atomic with respect to every other operation on
+// the ConcurrentTree. Keys whose value has expired count as absent. Values are stored with the
+// tree's default TTL, and a tree created with a capacity evicts afterwards, as with Insert.
+
+// InsertIfAbsent stores val under str unless the key already exists, and reports whether it did.
+func (t *ConcurrentTree[K, T]) InsertIfAbsent(str []K, val T) bool {
+	_, loaded := t.GetOrInsert(str, val)
+	return !loaded && len(str) > 0
+}
+
+// GetOrInsert returns the value stored under str and true if the key exists. Otherwise it
+// stores val and returns the stored value and false. An empty key is never stored and
+// returns nil and false.
+func (t *ConcurrentTree[K, T]) GetOrInsert(str []K, val T) (*T, bool) {
+	var actual *T
+	loaded := false
+	t.put(str, t.opts.ttl, func(old *T, exists bool) (*T, bool) {
+		if exists {
+			actual, loaded = old, true
+			return nil, false
+		}
+		actual = &val
+		return actual, true
+	})
+	return actual, loaded
+}
+
+// Update stores the value returned by fn under str and returns it. fn is called with the
+// current value of the key and whether it exists, while the tree holds the lock of the node
+// storing the key, so it must be quick and must not call back into the tree.
+func (t *ConcurrentTree[K, T]) Update(str []K, fn func(old *T, exists bool) T) *T {
+	var stored *T
+	t.put(str, t.opts.ttl, func(old *T, exists bool) (*T, bool) {
+		val := fn(old, exists)
+		stored = &val
+		return stored, true
+	})
+	return stored
+}
+
+// CompareAndSwap stores new under str if the key exists and equal reports its current value
+// to be equal to old, and reports whether it did. equal is called under the tree's locks and
+// must not call back into the tree.
+func (t *ConcurrentTree[K, T]) CompareAndSwap(str []K, old, new T, equal func(a, b T) bool) bool {
+	swapped := false
+	t.put(str, t.opts.ttl, func(cur *T, exists bool) (*T, bool) {
+		if !exists || !equal(*cur, old) {
+			return nil, false
+		}
+		swapped = true
+		return &new, true
+	})
+	return swapped
+}
+
+// CompareAndDelete removes str through RemoveNode if the key exists and equal reports its
+// current value to be equal to old, and reports whether it did. Pinned keys are never deleted,
+// and are reported as false like a missing key; use TryCompareAndDelete to tell them apart.
+// equal is called under the tree's locks and must not call back into the tree.
+func (t *ConcurrentTree[K, T]) CompareAndDelete(str []K, old T, equal func(a, b T) bool) bool {
+	deleted, _ := t.TryCompareAndDelete(str, old, equal)
+	return deleted
+}
+
+// TryCompareAndDelete is CompareAndDelete, but returns ErrPinned if the key holds a value equal
+// to old and is held by a Pin handle, as RemoveNode does.
+func (t *ConcurrentTree[K, T]) TryCompareAndDelete(str []K, old T, equal func(a, b T) bool) (bool, error) {
+	return t.deleteIf(str, func(node *ConcurrentNode[K, T]) bool {
+		return node.End && !expired(node.expires, t.expiryClock()) && equal(*node.Val, old)
+	})
+}
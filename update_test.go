@@ -0,0 +1,142 @@
+package lradix
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentTreeConditionalWrites(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tree := NewConcurrentTree[byte, int](WithClock(clock.Now))
+	equal := func(a, b int) bool { return a == b }
+
+	if !tree.InsertIfAbsent([]byte("hello"), 1) {
+		t.Error("InsertIfAbsent(hello) failed on an empty tree")
+	}
+	if tree.InsertIfAbsent([]byte("hello"), 2) {
+		t.Error("InsertIfAbsent(hello) overwrote an existing key")
+	}
+	if tree.InsertIfAbsent(nil, 2) {
+		t.Error("InsertIfAbsent stored an empty key")
+	}
+	// absent keys ending inside a node's text, at an intermediate node and below a leaf
+	for _, key := range []string{"he", "help", "hel", "hello world"} {
+		if val, loaded := tree.GetOrInsert([]byte(key), len(key)); loaded || *val != len(key) {
+			t.Errorf("GetOrInsert(%q) = %v, %v, expected the inserted value", key, *val, loaded)
+		}
+	}
+	if val, loaded := tree.GetOrInsert([]byte("hello"), 3); !loaded || *val != 1 {
+		t.Errorf("GetOrInsert(hello) = %v, %v, expected 1, true", *val, loaded)
+	}
+
+	if tree.CompareAndSwap([]byte("hello"), 2, 10, equal) {
+		t.Error("CompareAndSwap(hello) swapped a different value")
+	}
+	if tree.CompareAndSwap([]byte("missing"), 0, 10, equal) {
+		t.Error("CompareAndSwap swapped a missing key")
+	}
+	if !tree.CompareAndSwap([]byte("hello"), 1, 10, equal) {
+		t.Error("CompareAndSwap(hello) failed with the current value")
+	}
+	if val := tree.Update([]byte("hello"), func(old *int, exists bool) int {
+		if !exists {
+			t.Error("Update(hello) reported an existing key as absent")
+			return 0
+		}
+		return *old + 1
+	}); *val != 11 {
+		t.Errorf("Update(hello) = %d, expected 11", *val)
+	}
+	if val := tree.Update([]byte("new"), func(old *int, exists bool) int {
+		if exists {
+			t.Error("Update(new) reported a missing key as present")
+		}
+		return 7
+	}); *val != 7 {
+		t.Errorf("Update(new) = %d, expected 7", *val)
+	}
+
+	if tree.CompareAndDelete([]byte("hello"), 1, equal) {
+		t.Error("CompareAndDelete(hello) deleted a different value")
+	}
+	if tree.CompareAndDelete([]byte("he"), 3, equal) {
+		t.Error("CompareAndDelete(he) deleted a different value")
+	}
+	if !tree.CompareAndDelete([]byte("hello"), 11, equal) {
+		t.Error("CompareAndDelete(hello) failed with the current value")
+	}
+	if _, ok := tree.Get([]byte("hello")); ok {
+		t.Error("CompareAndDelete(hello) left the key in the tree")
+	}
+	if val, ok := tree.Get([]byte("hello world")); !ok || *val != 11 {
+		t.Errorf("Get(hello world) = %v, %v, expected the keys below hello to be kept", val, ok)
+	}
+	h := tree.Pin([]byte("help"))
+	if tree.CompareAndDelete([]byte("help"), 4, equal) {
+		t.Error("CompareAndDelete deleted a pinned key")
+	}
+	tree.Unpin(h)
+
+	// an expired key counts as absent
+	tree.InsertWithTTL([]byte("lease"), 1, time.Second)
+	clock.Advance(time.Second)
+	if tree.CompareAndSwap([]byte("lease"), 1, 2, equal) {
+		t.Error("CompareAndSwap swapped an expired value")
+	}
+	if !tree.InsertIfAbsent([]byte("lease"), 3) {
+		t.Error("InsertIfAbsent failed on an expired key")
+	}
+	if val, ok := tree.Get([]byte("lease")); !ok || *val != 3 {
+		t.Errorf("Get(lease) = %v, %v, expected 3, true", val, ok)
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentTreeConditionalWritesConcurrent(t *testing.T) {
+	tree := NewConcurrentTree[byte, int]()
+	equal := func(a, b int) bool { return a == b }
+	keys := []string{"counter", "count", "co", "cat"}
+	var wg sync.WaitGroup
+	var inserted sync.Map
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				key := []byte(keys[i%len(keys)])
+				if tree.InsertIfAbsent(append(key, "-once"...), g) {
+					if _, dup := inserted.LoadOrStore(string(key), g); dup {
+						t.Errorf("InsertIfAbsent(%s-once) succeeded twice", key)
+					}
+				}
+				tree.Update(key, func(old *int, exists bool) int {
+					if !exists {
+						return 1
+					}
+					return *old + 1
+				})
+				for {
+					old, _ := tree.GetOrInsert(append(key, "-cas"...), 0)
+					if tree.CompareAndSwap(append(key, "-cas"...), *old, *old+1, equal) {
+						break
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for _, key := range keys {
+		for _, suffix := range []string{"", "-cas"} {
+			if val, ok := tree.Get([]byte(key + suffix)); !ok || *val != 8*200/len(keys) {
+				t.Errorf("Get(%s%s) = %v, %v, expected %d", key, suffix, *val, ok, 8*200/len(keys))
+			}
+		}
+	}
+	if err := tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,179 @@
+package lradix
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Violation describes a single broken structural invariant.
+type Violation struct {
+	Path   string // Reconstructed key of the offending node, as displayed by String
+	Reason string // What is wrong with the node
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("lradix: node %q: %s", v.Path, v.Reason)
+}
+
+// ValidationError lists every violation found by Validate.
+// The individual violations can be inspected with errors.As.
+type ValidationError struct {
+	Violations []*Violation
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Violations)+1)
+	lines = append(lines, fmt.Sprintf("lradix: %d invariant violation(s)", len(e.Violations)))
+	for _, v := range e.Violations {
+		lines = append(lines, "  "+v.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Violations))
+	for i, v := range e.Violations {
+		errs[i] = v
+	}
+	return errs
+}
+
+// validator accumulates violations over a tree walk.
+type validator struct {
+	violations []*Violation
+}
+
+func (v *validator) report(path string, format string, args ...any) {
+	v.violations = append(v.violations, &Violation{Path: path, Reason: fmt.Sprintf(format, args...)})
+}
+
+// checkNode verifies the invariants of a single node that do not involve its children.
+func checkNode[K comparable, T any](v *validator, key []K, isRoot bool, text []K, val *T, end bool, children int) {
+	path := displayText(key)
+	if isRoot {
+		if len(text) != 0 {
+			v.report(path, "root has text %s", displayText(text))
+		}
+		if end {
+			v.report(path, "root is marked as End")
+		}
+		if val != nil {
+			v.report(path, "root has a value")
+		}
+		return
+	}
+	if len(text) == 0 {
+		v.report(path, "non-root node has empty text")
+	}
+	if end && val == nil {
+		v.report(path, "End node has no value")
+	}
+	if !end && children < 2 {
+		v.report(path, "intermediate node has %d child(ren), expected at least 2", children)
+	}
+}
+
+// checkChild verifies that child is correctly linked below parent under head.
+func checkChild[K comparable](v *validator, key []K, head K, child bool, text []K, parentOK bool) {
+	path := displayText(key)
+	if !child {
+		v.report(path, "nil child under %s", displayText([]K{head}))
+		return
+	}
+	if len(text) > 0 && text[0] != head {
+		v.report(path, "child is keyed by %s but its text starts with %s", displayText([]K{head}), displayText(text[:1]))
+	}
+	if !parentOK {
+		v.report(path, "Parent pointer does not point to the node holding it as a child")
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: v.violations}
+}
+
+// Validate checks the structural invariants of the tree and returns a *ValidationError
+// listing every violation, or nil if the tree is healthy:
+//   - the root has no text, no value, is not an End node and has no Parent
+//   - every child's Parent points back to the node holding it, keyed by the child's first text element
+//   - no non-root node has empty text
+//   - every intermediate node has at least two children, so chains are compacted
+//   - every End node has a value
+func (t *Tree[K, T]) Validate() error {
+	v := &validator{}
+	if t.Root.Parent != nil {
+		v.report(displayText([]K{}), "root has a Parent")
+	}
+	var visit func(node *Node[K, T], key []K, isRoot bool)
+	visit = func(node *Node[K, T], key []K, isRoot bool) {
+		checkNode(v, key, isRoot, node.Text, node.Val, node.End, node.children.len())
+		for _, head := range childOrder(&node.children, compareStable[K]) {
+			child, _ := node.GetChild(head)
+			if child == nil {
+				checkChild(v, key, head, false, nil, false)
+				continue
+			}
+			childKey := append(key[:len(key):len(key)], child.Text...)
+			checkChild(v, childKey, head, true, child.Text, child.Parent == node)
+			visit(child, childKey, false)
+		}
+	}
+	visit(t.Root, []K{}, true)
+	return v.err()
+}
+
+// Validate checks the structural invariants of the tree, as Tree.Validate does, except that
+// pinned intermediate nodes may have fewer than two children. In a bounded tree it also checks
+// that the LRU heap holds exactly the unpinned leaves.
+// Writers are paused during the walk, as in Snapshot, so the result is consistent.
+func (t *ConcurrentTree[K, T]) Validate() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lruMu.Lock()
+	defer t.lruMu.Unlock()
+	v := &validator{}
+	leaves := 0
+	if t.Root.Parent != nil {
+		v.report(displayText([]K{}), "root has a Parent")
+	}
+	var visit func(node *ConcurrentNode[K, T], key []K, isRoot bool)
+	visit = func(node *ConcurrentNode[K, T], key []K, isRoot bool) {
+		children := node.children.len()
+		if node.pins > 0 {
+			// pinned intermediate nodes are only compacted or removed once unpinned
+			children = max(children, 2)
+		}
+		checkNode(v, key, isRoot, node.Text, node.Val, node.End, children)
+		if t.bounded() {
+			evictable := !isRoot && node.End && node.children.len() == 0 && node.pins == 0
+			slot := node.lruSlot.Load()
+			switch {
+			case evictable && (slot == 0 || slot > int64(len(t.lru)) || t.lru[slot-1] != node):
+				v.report(displayText(key), "evictable leaf is missing from the LRU heap")
+			case !evictable && slot > 0:
+				v.report(displayText(key), "node in the LRU heap is not an evictable leaf")
+			}
+			if evictable {
+				leaves++
+			}
+		}
+		for _, head := range childOrder(&node.children, compareStable[K]) {
+			child, _ := node.GetChild(head)
+			if child == nil {
+				checkChild(v, key, head, false, nil, false)
+				continue
+			}
+			childKey := append(key[:len(key):len(key)], child.Text...)
+			checkChild(v, childKey, head, true, child.Text, child.Parent == node)
+			visit(child, childKey, false)
+		}
+	}
+	visit(t.Root, []K{}, true)
+	if leaves != len(t.lru) {
+		v.report(displayText([]K{}), "LRU heap holds %d nodes, expected %d evictable leaves", len(t.lru), leaves)
+	}
+	return v.err()
+}
@@ -0,0 +1,187 @@
+package lradix
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tree := NewTree[byte, int]()
+	if err := tree.Validate(); err != nil {
+		t.Errorf("empty tree: unexpected error %v", err)
+	}
+	for i, key := range []string{"hello", "help", "hel", "he", "world", "wor", "a"} {
+		tree.Insert([]byte(key), i)
+		if err := tree.Validate(); err != nil {
+			t.Fatalf("after Insert(%q): %v", key, err)
+		}
+	}
+	for _, key := range []string{"hel", "help", "wor", "he", "hello", "a", "world"} {
+		tree.Delete([]byte(key))
+		if err := tree.Validate(); err != nil {
+			t.Fatalf("after Delete(%q): %v\n%s", key, err, tree.String())
+		}
+	}
+	if tree.Len() != 0 || tree.Root.NumChildren() != 0 {
+		t.Errorf("Expected empty tree, got\n%s", tree.String())
+	}
+}
+
+func TestValidateViolations(t *testing.T) {
+	testCases := []struct {
+		name     string
+		corrupt  func(tree *Tree[byte, int])
+		path     string
+		contains string
+	}{
+		{
+			name: "wrong parent",
+			corrupt: func(tree *Tree[byte, int]) {
+				childOf(childOf(tree.Root, 'h'), 'l').Parent = tree.Root
+			},
+			path:     "hello",
+			contains: "Parent pointer",
+		},
+		{
+			name: "wrong child key",
+			corrupt: func(tree *Tree[byte, int]) {
+				node := childOf(tree.Root, 'w')
+				tree.Root.RemoveChild('w')
+				tree.Root.children.put('x', node)
+			},
+			path:     "world",
+			contains: "keyed by x",
+		},
+		{
+			name: "empty text",
+			corrupt: func(tree *Tree[byte, int]) {
+				childOf(tree.Root, 'w').Text = []byte{}
+			},
+			path:     "ROOT",
+			contains: "empty text",
+		},
+		{
+			name: "uncompacted intermediate node",
+			corrupt: func(tree *Tree[byte, int]) {
+				childOf(tree.Root, 'h').RemoveChild('l')
+			},
+			path:     "hel",
+			contains: "1 child(ren)",
+		},
+		{
+			name: "End node without value",
+			corrupt: func(tree *Tree[byte, int]) {
+				childOf(tree.Root, 'w').Val = nil
+			},
+			path:     "world",
+			contains: "no value",
+		},
+		{
+			name: "root marked as End",
+			corrupt: func(tree *Tree[byte, int]) {
+				tree.Root.End = true
+			},
+			path:     "ROOT",
+			contains: "root is marked as End",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tree := NewTree[byte, int]()
+			for i, key := range []string{"hello", "help", "world"} {
+				tree.Insert([]byte(key), i)
+			}
+			tc.corrupt(tree)
+			err := tree.Validate()
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected *ValidationError, got %v", err)
+			}
+			found := false
+			for _, v := range verr.Violations {
+				if v.Path == tc.path && strings.Contains(v.Reason, tc.contains) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected violation at %q containing %q, got:\n%v", tc.path, tc.contains, err)
+			}
+			var violation *Violation
+			if !errors.As(err, &violation) {
+				t.Errorf("Expected errors.As to find a *Violation")
+			}
+		})
+	}
+}
+
+func TestValidateRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tree := NewTree[byte, int]()
+	ctree := NewConcurrentTree[byte, int]()
+	keys := []string{}
+	for i := 0; i < 2000; i++ {
+		if len(keys) > 0 && r.Intn(3) == 0 {
+			key := keys[r.Intn(len(keys))]
+			tree.Delete([]byte(key))
+			ctree.Delete([]byte(key))
+		} else {
+			key := randomKey(r)
+			keys = append(keys, key)
+			tree.Insert([]byte(key), i)
+			ctree.Insert([]byte(key), i)
+		}
+		if err := tree.Validate(); err != nil {
+			t.Fatalf("Tree step %d: %v", i, err)
+		}
+		if err := ctree.Validate(); err != nil {
+			t.Fatalf("ConcurrentTree step %d: %v", i, err)
+		}
+	}
+	if tree.Len() != ctree.Len() {
+		t.Errorf("Len() = %d and %d, expected equal", tree.Len(), ctree.Len())
+	}
+}
+
+func randomKey(r *rand.Rand) string {
+	b := make([]byte, 1+r.Intn(6))
+	for i := range b {
+		b[i] = "abc"[r.Intn(3)]
+	}
+	return string(b)
+}
+
+func TestConcurrentTreeValidateConcurrent(t *testing.T) {
+	tree := NewConcurrentTree[byte, int]()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(g)))
+			for i := 0; i < 500; i++ {
+				key := []byte(fmt.Sprintf("%s%d", randomKey(r), g))
+				tree.Insert(key, i)
+				if r.Intn(2) == 0 {
+					tree.Delete(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if err := tree.Validate(); err != nil {
+		t.Fatalf("Validate after concurrent writes: %v", err)
+	}
+	count := 0
+	tree.Walk(func(key []byte, val *int) bool {
+		count++
+		return true
+	})
+	if count != tree.Len() {
+		t.Errorf("Walk visited %d keys, Len() = %d", count, tree.Len())
+	}
+}
@@ -0,0 +1,123 @@
+package lradix
+
+import "fmt"
+
+// ValuePolicy decides which value an intermediate node carries. Intermediate nodes do not
+// represent a stored key, but their value is what LongestCommonPrefixMatch returns when
+// the match stops at or inside them. The value is derived from the node's children,
+// where each child contributes its own value if it is an End node or its derived value
+// otherwise, and it is kept up to date on insert, split and removal.
+// End nodes always keep the value stored under their key.
+type ValuePolicy int
+
+const (
+	// ValueMostRecent gives intermediate nodes the most recently inserted value among their children.
+	// This is the default policy.
+	ValueMostRecent ValuePolicy = iota
+	// ValueFirstInserted gives intermediate nodes the earliest inserted value among their children.
+	ValueFirstInserted
+	// ValueNone leaves intermediate nodes without a value.
+	ValueNone
+	// ValueReduce combines the children's values with a reducer set by WithValueReducer.
+	ValueReduce
+)
+
+func (p ValuePolicy) String() string {
+	switch p {
+	case ValueMostRecent:
+		return "MostRecent"
+	case ValueFirstInserted:
+		return "FirstInserted"
+	case ValueNone:
+		return "None"
+	case ValueReduce:
+		return "Reduce"
+	}
+	return fmt.Sprintf("ValuePolicy(%d)", int(p))
+}
+
+// WithValuePolicy selects one of the built-in value policies for intermediate nodes.
+// Use WithValueReducer for ValueReduce.
+func WithValuePolicy(policy ValuePolicy) Option {
+	return func(o *treeOptions) {
+		o.valuePolicy = policy
+	}
+}
+
+// WithValueReducer gives intermediate nodes the value returned by reduce, called with the
+// values of the node's children, some of which may be nil. The reducer runs under the tree's
+// locks in ConcurrentTree and must not call back into the tree. T must match the value type
+// of the tree, otherwise NewTree and NewConcurrentTree panic.
+func WithValueReducer[T any](reduce func(children []*T) *T) Option {
+	return func(o *treeOptions) {
+		o.valuePolicy = ValueReduce
+		o.valueReducer = reduce
+	}
+}
+
+// valuePolicy is the typed form of the value policy options used by a tree.
+type valuePolicy[T any] struct {
+	kind   ValuePolicy
+	reduce func(children []*T) *T
+}
+
+func newValuePolicy[T any](o treeOptions) valuePolicy[T] {
+	p := valuePolicy[T]{kind: o.valuePolicy}
+	if p.kind == ValueReduce {
+		reduce, ok := o.valueReducer.(func(children []*T) *T)
+		if !ok {
+			panic(fmt.Sprintf("lradix: value reducer %T does not match value type %T", o.valueReducer, *new(T)))
+		}
+		p.reduce = reduce
+	}
+	return p
+}
+
+// valueAcc accumulates the values of a node's children, along with the insertion
+// sequence numbers that order them, and derives the node's value from them.
+type valueAcc[T any] struct {
+	policy *valuePolicy[T]
+	val    *T
+	seq    uint64
+	vals   []*T // all values, only collected for ValueReduce
+}
+
+func (a *valueAcc[T]) add(val *T, seq uint64) {
+	switch a.policy.kind {
+	case ValueMostRecent:
+		if val != nil && (a.val == nil || seq > a.seq) {
+			a.val, a.seq = val, seq
+		}
+	case ValueFirstInserted:
+		if val != nil && (a.val == nil || seq < a.seq) {
+			a.val, a.seq = val, seq
+		}
+	case ValueReduce:
+		a.vals = append(a.vals, val)
+	}
+}
+
+func (a *valueAcc[T]) result() (*T, uint64) {
+	if a.policy.kind == ValueReduce {
+		return a.policy.reduce(a.vals), 0
+	}
+	return a.val, a.seq
+}
+
+// skipOnInsert reports whether storing a value with sequence number seq below an intermediate
+// node holding val and valSeq leaves the node's value unchanged, so that the built-in policies do
+// not have to re-read every child. Otherwise insertedWins reports whether the inserted value
+// becomes the node's value without looking at the other children. leaf is set when the value
+// was stored in a new leaf, so that no value already below the node was replaced.
+func (p *valuePolicy[T]) skipOnInsert(val *T, valSeq uint64, seq uint64, leaf bool) (skip bool, insertedWins bool) {
+	switch p.kind {
+	case ValueMostRecent:
+		return val != nil && valSeq >= seq, true
+	case ValueFirstInserted:
+		// every value already below the node is older than a new leaf
+		return leaf && val != nil, false
+	case ValueNone:
+		return true, false
+	}
+	return false, false
+}